@@ -0,0 +1,167 @@
+package gomq
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/RohanPoojary/gomq/queue"
+)
+
+// ErrDeliverySettled is returned on acknowledging a delivery,
+// which is already acknowledged, rejected or redelivered.
+var ErrDeliverySettled = errors.New("gomq: delivery already settled")
+
+// Delivery is a message polled from a subscription, which is to be acknowledged once processed.
+//
+// For subscriptions without acknowledgement mode, the delivery is considered acknowledged once polled,
+// though it can still be rejected to its dead letter topic.
+type Delivery struct {
+	*Message
+
+	// Attempt is the count of times the message has been delivered to the subscription.
+	// It starts from 1, and is incremented on every redelivery.
+	Attempt int
+
+	tracker    *tracker
+	tag        uint64
+	deadLetter *deadLetter
+}
+
+// Ack acknowledges the message has been processed, hence it will not be redelivered.
+func (d *Delivery) Ack() error {
+	if d.tracker == nil {
+		return nil
+	}
+
+	return d.tracker.settle(d)
+}
+
+// Nack acknowledges the message could not be processed.
+// If requeue is true, the message is redelivered to the head of the subscription,
+// else it is rejected similar to Reject, returning its error.
+func (d *Delivery) Nack(requeue bool) error {
+	if !requeue {
+		return d.Reject(ReasonRejected)
+	}
+
+	if d.tracker == nil {
+		return nil
+	}
+
+	if err := d.tracker.settle(d); err != nil {
+		return err
+	}
+
+	d.tracker.requeue(d)
+	return nil
+}
+
+// Reject acknowledges the message cannot be processed, hence it will not be redelivered.
+// The message is published to the dead letter topic of the subscription along with the reason,
+// else it is discarded.
+//
+// It returns the error, if the message couldn't be published to the dead letter topic,
+// such as an error wrapping ErrTypeMismatch if the Registry rejects it,
+// or a PublishError if a subscription of the dead letter topic is full.
+// The message is settled regardless, and counted by SubscriptionStats.DeadLettersDropped.
+//
+// For AsyncBroker, the dead letter is published asynchronously,
+// hence only the errors on queueing it are returned, though all the dropped dead letters are counted.
+func (d *Delivery) Reject(reason string) error {
+	if d.tracker != nil {
+		if err := d.tracker.settle(d); err != nil {
+			return err
+		}
+	}
+
+	if reason == "" {
+		reason = ReasonRejected
+	}
+
+	return d.deadLetter.send(d.Message, d.Attempt, reason)
+}
+
+// tracker tracks the unacknowledged deliveries of a subscription,
+// and redelivers them once their deadline elapses.
+type tracker struct {
+	queue         queue.Queue
+	deadline      time.Duration
+	maxDeliveries int
+	deadLetter    *deadLetter
+
+	sync.Mutex
+	tags    uint64
+	pending map[uint64]*time.Timer
+}
+
+func newTracker(que queue.Queue, deadline time.Duration, maxDeliveries int, dl *deadLetter) *tracker {
+	return &tracker{
+		queue:         que,
+		deadline:      deadline,
+		maxDeliveries: maxDeliveries,
+		deadLetter:    dl,
+		pending:       map[uint64]*time.Timer{},
+	}
+}
+
+// track creates a delivery, which is redelivered unless settled within the deadline.
+func (t *tracker) track(msg *Message, attempt int) *Delivery {
+	t.Lock()
+	defer t.Unlock()
+
+	t.tags++
+	d := &Delivery{Message: msg, Attempt: attempt, tracker: t, tag: t.tags, deadLetter: t.deadLetter}
+	t.pending[d.tag] = time.AfterFunc(t.deadline, func() {
+		if t.settle(d) == nil {
+			t.requeue(d)
+		}
+	})
+
+	return d
+}
+
+// settle stops tracking the delivery.
+func (t *tracker) settle(d *Delivery) error {
+	t.Lock()
+	defer t.Unlock()
+
+	timer, ok := t.pending[d.tag]
+	if !ok {
+		return ErrDeliverySettled
+	}
+
+	timer.Stop()
+	delete(t.pending, d.tag)
+
+	return nil
+}
+
+// requeue pushes the delivery back to the head of the queue.
+// The delivery is pushed rather than its message, to keep track of its attempts.
+//
+// If the message has been delivered the maximum number of times, then it is dead lettered.
+func (t *tracker) requeue(d *Delivery) {
+	if t.maxDeliveries > 0 && d.Attempt >= t.maxDeliveries {
+		t.deadLetter.send(d.Message, d.Attempt, ReasonMaxDeliveries)
+		return
+	}
+
+	t.queue.PushFront(d)
+}
+
+// close stops tracking all the pending deliveries.
+// It is a no-op for a nil tracker.
+func (t *tracker) close() {
+	if t == nil {
+		return
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	for tag, timer := range t.pending {
+		timer.Stop()
+		delete(t.pending, tag)
+	}
+}
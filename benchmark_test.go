@@ -107,3 +107,75 @@ func BenchmarkPoll(b *testing.B) {
 		})
 	}
 }
+
+func benchmarkPublishNSubscriptions(b *testing.B, creator func() Broker, n int) {
+
+	broker := creator()
+	defer broker.Close(0)
+
+	// Every subscription has a distinct topic, hence a publish matches only one of them.
+	for i := 0; i < n; i++ {
+		sub := broker.Subscribe(MustTopicMatcher("test." + strconv.Itoa(i) + ".*"))
+		go func() {
+			for _, ok := sub.Poll(); ok; _, ok = sub.Poll() {
+			}
+		}()
+	}
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			broker.Publish("test."+strconv.Itoa(rand.Intn(n))+".created", rand.Intn(1000))
+		}
+	})
+}
+
+func BenchmarkPublishIndexed(b *testing.B) {
+
+	subscriptionCounts := []int{10, 100, 1000}
+
+	for _, subscriptionCount := range subscriptionCounts {
+		name := fmt.Sprintf("SyncBroker/Subscriptions=%d", subscriptionCount)
+		b.Run(name, func(b *testing.B) {
+			benchmarkPublishNSubscriptions(b, NewBroker, subscriptionCount)
+		})
+	}
+
+	for _, subscriptionCount := range subscriptionCounts {
+		name := fmt.Sprintf("AsyncBroker/Subscriptions=%d", subscriptionCount)
+		b.Run(name, func(b *testing.B) {
+			benchmarkPublishNSubscriptions(b, NewAsyncBroker, subscriptionCount)
+		})
+	}
+}
+
+func BenchmarkSubscribeUnsubscribe(b *testing.B) {
+
+	subscriptionCounts := []int{100, 1000, 10000}
+
+	for _, subscriptionCount := range subscriptionCounts {
+		name := fmt.Sprintf("Subscriptions=%d", subscriptionCount)
+		b.Run(name, func(b *testing.B) {
+			broker := NewBroker()
+			defer broker.Close(0)
+
+			// Half of the subscriptions are on distinct exact topics, the rest on distinct patterns.
+			for i := 0; i < subscriptionCount; i++ {
+				if i%2 == 0 {
+					broker.Subscribe(ExactMatcher("reply." + strconv.Itoa(i)))
+				} else {
+					broker.Subscribe(MustTopicMatcher("test." + strconv.Itoa(i) + ".*"))
+				}
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				sub := broker.Subscribe(ExactMatcher("reply.request"))
+				sub.Unsubscribe(0)
+			}
+		})
+	}
+}
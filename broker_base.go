@@ -1,37 +1,412 @@
 package gomq
 
 import (
+	"context"
+	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/RohanPoojary/gomq/queue"
 )
 
 type queueMatcher struct {
+	// id orders the subscribers by their subscription.
+	id uint64
+
 	queue   queue.Queue
-	matcher Matcher
+	matcher MessageMatcher
+	name    string
+	durable bool
+
+	// filter is nil, unless the messages are filtered on publishing.
+	filter MessageMatcher
+
+	// ttl is the duration after which the messages expire, unless it is zero.
+	ttl time.Duration
+
+	// stats is shared by all the Subscriptions of the queue.
+	stats *subscriptionStats
+
+	// tracker tracks the unacknowledged deliveries, it is nil unless in acknowledgement mode.
+	tracker *tracker
+
+	// deadLetter is nil, unless a dead letter topic is configured.
+	deadLetter *deadLetter
+
+	// group is nil, unless the queue is shared by a consumer group.
+	group *consumerGroup
+}
+
+// matches returns true, if the message is to be published to the queue.
+func (qm *queueMatcher) matches(msg *Message) bool {
+	return qm.matcher.MatchMessage(msg) && (qm.filter == nil || qm.filter.MatchMessage(msg))
+}
+
+// consumerGroup tracks the members sharing a queue.
+// It is modified only while holding the broker's lock.
+type consumerGroup struct {
+	name    string
+	members int
 }
 
 type brokerBase struct {
-	queueMatchers []queueMatcher
+	// published is the count of messages published, used for generating message IDs.
+	// It is accessed atomically, hence kept first for 64 bit alignment.
+	published uint64
+
+	// index is replaced on every change rather than being modified in place,
+	// hence a snapshot of it can be read without holding the lock.
+	index *subscriptionIndex
 	sync.RWMutex
+
+	// subscriptions holds the subscribers by their queue, it is guarded by the lock.
+	subscriptions map[queue.Queue]*queueMatcher
+
+	// subscribed is the count of subscriptions created, used for naming them.
+	subscribed uint64
+
+	// registry is nil, unless the payloads are validated against their registered types.
+	registry *Registry
+
+	// closed is set once the broker is closed, hence further publishes are rejected.
+	closed bool
+
+	// scheduler publishes the messages published by PublishAt.
+	scheduler *scheduler
+
+	// deadLetters publishes the dead lettered messages, without waiting for space in full subscriptions.
+	// It invokes dropped, if the message isn't published to all the subscribers of the dead letter topic.
+	deadLetters func(msg *Message, dropped func()) error
+
+	// retained holds the last retained message of every topic.
+	// It is guarded by the lock along with index, hence a new subscription receives every retained message exactly once.
+	retained map[string]*Message
+
+	// histories configures the topics, whose messages are kept for replaying.
+	histories []historyConfig
+
+	// logs holds the history of every topic configured by histories, it is guarded by the lock along with index.
+	// The histories emptied by their retention are pruned, once the count of logs reaches pruneAt.
+	logs    map[string]*topicHistory
+	pruneAt int
+}
+
+func (b *brokerBase) Subscribe(matcher Matcher) Subscription {
+	return b.SubscribeWithOptions(matcher)
+}
+
+func (b *brokerBase) SubscribeGroup(group string, matcher Matcher) Subscription {
+	return b.SubscribeWithOptions(matcher, WithGroup(group))
 }
 
-func (b *brokerBase) Subscribe(matcher Matcher) Poller {
+func (b *brokerBase) SubscribeWithOptions(matcher Matcher, opts ...SubscribeOption) Subscription {
+	return b.SubscribeMessage(MatchTopic(matcher), opts...)
+}
+
+func (b *brokerBase) SubscribeMessage(matcher MessageMatcher, opts ...SubscribeOption) Subscription {
+	cfg := newSubscribeConfig(opts)
 
 	b.Lock()
 	defer b.Unlock()
 
-	que := queue.NewQueue()
-	b.queueMatchers = append(b.queueMatchers, queueMatcher{queue: que, matcher: matcher})
+	if cfg.group != "" {
+		for _, qm := range b.subscriptions {
+			if qm.group != nil && qm.group.name == cfg.group {
+				qm.group.members++
+				return &subscription{queueMatcher: qm, broker: b}
+			}
+		}
+	}
+
+	if cfg.durable && cfg.name != "" {
+		for _, qm := range b.subscriptions {
+			if qm.durable && qm.name == cfg.name {
+				return &subscription{queueMatcher: qm, broker: b}
+			}
+		}
+	}
+
+	b.subscribed++
+	if cfg.name == "" && cfg.group != "" {
+		cfg.name = cfg.group
+	} else if cfg.name == "" {
+		cfg.name = "subscription-" + strconv.FormatUint(b.subscribed, 10)
+	}
 
-	return que
+	qm := &queueMatcher{
+		id:      b.subscribed,
+		queue:   queue.NewQueueWithOptions(cfg.queue),
+		matcher: matcher,
+		name:    cfg.name,
+		durable: cfg.durable,
+		filter:  cfg.filter,
+		ttl:     cfg.ttl,
+		stats:   &subscriptionStats{},
+	}
+
+	if cfg.deadLetterTopic != "" {
+		qm.deadLetter = &deadLetter{
+			topic:        cfg.deadLetterTopic,
+			subscription: qm.name,
+			publish:      b.deadLetters,
+			stats:        qm.stats,
+		}
+	}
+
+	if cfg.ackDeadline > 0 {
+		qm.tracker = newTracker(qm.queue, cfg.ackDeadline, cfg.maxDeliveries, qm.deadLetter)
+	}
+
+	if cfg.group != "" {
+		qm.group = &consumerGroup{name: cfg.group, members: 1}
+	}
+
+	// The subscription is closed if the broker is closed, hence polling it returns rather than waiting forever.
+	if b.closed {
+		qm.queue.Close(0)
+		return &subscription{queueMatcher: qm, broker: b}
+	}
+
+	if b.subscriptions == nil {
+		b.subscriptions = map[queue.Queue]*queueMatcher{}
+	}
+
+	b.subscriptions[qm.queue] = qm
+	b.index = b.index.with(qm)
+	b.unsafeEnqueue(qm, append(b.unsafeReplay(qm, cfg.start), b.unsafeRetained(qm)...))
+
+	return &subscription{queueMatcher: qm, broker: b}
+}
+
+// unsubscribe detaches the queue from the broker, so that no further data is published to it.
+// For a consumer group, the queue is detached only once all its members have unsubscribed.
+//
+// It returns true, if the queue has been detached.
+func (b *brokerBase) unsubscribe(que queue.Queue) bool {
+
+	b.Lock()
+	defer b.Unlock()
+
+	qm, ok := b.subscriptions[que]
+	if !ok {
+		return true
+	}
+
+	if qm.group != nil {
+		if qm.group.members--; qm.group.members > 0 {
+			return false
+		}
+	}
+
+	delete(b.subscriptions, que)
+	b.index = b.index.without(qm)
+	return true
+}
+
+// detach releases a member of the durable subscription, keeping its queue attached to the broker.
+// It returns false, if the subscription isn't durable.
+func (b *brokerBase) detach(qm *queueMatcher) bool {
+	if !qm.durable {
+		return false
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if qm.group != nil {
+		qm.group.members--
+	}
+
+	return true
+}
+
+// snapshot returns the current subscribers.
+// The lock is not held while publishing, as pushing to a bounded queue can block.
+func (b *brokerBase) snapshot() *subscriptionIndex {
+	b.RLock()
+	defer b.RUnlock()
+
+	return b.index
+}
+
+// record retains the message & adds it to the history of its topic, and returns the subscribers to publish it to.
+// All are done under the lock, hence a subscription created meanwhile receives the message exactly once.
+func (b *brokerBase) record(msg *Message, history *historyConfig) *subscriptionIndex {
+	b.Lock()
+	defer b.Unlock()
+
+	if history != nil {
+		b.unsafeRecord(msg, history, time.Now())
+	}
+
+	if msg.Retain {
+		b.unsafeRetain(msg)
+	}
+
+	return b.index
+}
+
+// unsafeEnqueue pushes the replayed & retained messages to the new subscription, in the order they are published.
+// A retained message also replayed is pushed once.
+//
+// It is called while holding the lock, hence it doesn't wait for space in a bounded subscription,
+// the messages beyond its capacity are dropped, unless its overflow policy is queue.DropOldest.
+func (b *brokerBase) unsafeEnqueue(qm *queueMatcher, messages []*Message) {
+	if len(messages) == 0 {
+		return
+	}
+
+	seen := make(map[*Message]bool, len(messages))
+	unique := messages[:0]
+	for _, msg := range messages {
+		if !seen[msg] {
+			seen[msg] = true
+			unique = append(unique, msg)
+		}
+	}
+
+	sort.SliceStable(unique, func(i, j int) bool {
+		return unique[i].Timestamp.Before(unique[j].Timestamp)
+	})
+
+	ctx := doneContext()
+	for _, msg := range unique {
+		qm.queue.PushContext(ctx, msg)
+	}
+}
+
+// doneContext returns a context, which is already done.
+// Hence, a push with it returns rather than waiting for space.
+func doneContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+// validate returns an error, if the message can't be published.
+func (b *brokerBase) validate(msg *Message) error {
+	if msg == nil {
+		return ErrInvalidMessage
+	}
+
+	if b.registry == nil {
+		return nil
+	}
+
+	return b.registry.Validate(msg.Topic, msg.Payload)
+}
+
+func (b *brokerBase) isClosed() bool {
+	b.RLock()
+	defer b.RUnlock()
+
+	return b.closed
+}
+
+// copyMessage copies the message published by the caller, hence the broker owns the copy.
+// A nil message is returned as is, which is rejected by validate.
+func copyMessage(msg *Message) *Message {
+	if msg == nil {
+		return nil
+	}
+
+	m := *msg
+	return &m
+}
+
+// stamp sets the ID & Timestamp of the message owned by the broker, if empty.
+func (b *brokerBase) stamp(msg *Message) *Message {
+	if msg.ID == "" {
+		msg.ID = strconv.FormatUint(atomic.AddUint64(&b.published, 1), 10)
+	}
+
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+
+	return msg
+}
+
+// publish pushes the message to all the subscribers matching its topic.
+// It returns ErrQueueFull along with the count, if any of the subscribers rejected the message as it is full.
+func (b *brokerBase) publish(msg *Message) (int, error) {
+	count, _, err := b.publishContext(context.Background(), msg)
+	return count, err
+}
+
+// publishContext is similar to publish, but waiting for space in full subscribers is stopped once ctx is done.
+// It returns the names of the subscribers which couldn't be served, along with the first cause.
+func (b *brokerBase) publishContext(ctx context.Context, msg *Message) (int, []string, error) {
+	var idx *subscriptionIndex
+	if history := b.historyConfig(msg.Topic); history != nil || msg.Retain {
+		idx = b.record(msg, history)
+	} else {
+		idx = b.snapshot()
+	}
+
+	// Avoids allocating for the usual count of matching subscribers.
+	var buf [16]*queueMatcher
+
+	var unserved []string
+	var cause error
+
+	count := 0
+	for _, qm := range idx.match(msg, buf[:0]) {
+		if qm.filter != nil && !qm.filter.MatchMessage(msg) {
+			continue
+		}
+
+		// A subscription closed meanwhile is no longer a subscriber, hence it isn't an error.
+		switch err := qm.queue.PushContext(ctx, msg); err {
+		case nil:
+			count += 1
+		case queue.ErrClosed:
+		default:
+			if cause == nil {
+				cause = err
+			}
+			unserved = append(unserved, qm.name)
+		}
+	}
+
+	return count, unserved, cause
+}
+
+func (b *brokerBase) PublishAt(topic string, data interface{}, at time.Time) error {
+	return b.publishAt(&Message{Topic: topic, Payload: data}, at)
+}
+
+func (b *brokerBase) PublishAfter(topic string, data interface{}, delay time.Duration) error {
+	return b.publishAt(&Message{Topic: topic, Payload: data}, time.Now().Add(delay))
+}
+
+func (b *brokerBase) PublishMessageAt(msg *Message, at time.Time) error {
+	// The message is copied, as it is published later.
+	return b.publishAt(copyMessage(msg), at)
+}
+
+// publishAt schedules the message owned by the broker.
+func (b *brokerBase) publishAt(msg *Message, at time.Time) error {
+	if err := b.validate(msg); err != nil {
+		return err
+	}
+
+	if b.isClosed() {
+		return ErrBrokerClosed
+	}
+
+	return b.scheduler.schedule(msg, at)
 }
 
 func (b *brokerBase) Close(timeOut time.Duration) {
+	b.scheduler.stop()
+
 	b.Lock()
 	defer b.Unlock()
 
+	b.closed = true
 	b.unsafeClose(timeOut)
 }
 
@@ -39,16 +414,18 @@ func (b *brokerBase) unsafeClose(timeOut time.Duration) {
 
 	wg := sync.WaitGroup{}
 
-	for _, qm := range b.queueMatchers {
+	for _, qm := range b.subscriptions {
 		qm := qm
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			qm.queue.Close(timeOut)
+			qm.tracker.close()
 		}()
 	}
 
 	wg.Wait() // Wait until all subscribers are closed.
 
-	b.queueMatchers = []queueMatcher{}
+	b.subscriptions = nil
+	b.index = newSubscriptionIndex()
 }
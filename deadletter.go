@@ -0,0 +1,69 @@
+package gomq
+
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+// Headers set on the messages published to a dead letter topic.
+const (
+	// HeaderDeathReason holds the reason for which the message was dead lettered.
+	HeaderDeathReason = "x-death-reason"
+
+	// HeaderOriginalTopic holds the topic to which the message was originally published.
+	HeaderOriginalTopic = "x-original-topic"
+
+	// HeaderSubscription holds the name of the subscription, which dead lettered the message.
+	HeaderSubscription = "x-subscription"
+
+	// HeaderDeliveryCount holds the count of times the message was delivered to the subscription.
+	HeaderDeliveryCount = "x-delivery-count"
+)
+
+// Reasons for which a message is dead lettered.
+const (
+	// ReasonRejected is the reason for messages rejected without a reason, or not acknowledged without requeue.
+	ReasonRejected = "rejected"
+
+	// ReasonMaxDeliveries is the reason for messages, which were delivered the maximum number of times.
+	ReasonMaxDeliveries = "max-deliveries"
+
+	// ReasonExpired is the reason for messages, which expired before being polled.
+	ReasonExpired = "expired"
+)
+
+// deadLetter publishes the messages, which a subscription couldn't process, to the dead letter topic.
+//
+// The messages are published by the consumer of the subscription, hence publish never waits for space.
+// The messages not published to all the subscribers of the dead letter topic are counted as dropped by stats.
+type deadLetter struct {
+	topic        string
+	subscription string
+	publish      func(msg *Message, dropped func()) error
+	stats        *subscriptionStats
+}
+
+// send publishes the message to the dead letter topic, along with the reason & its origin.
+// It returns the error, if the message couldn't be published.
+// It is a no-op for a nil deadLetter, in which case the message is discarded.
+func (dl *deadLetter) send(msg *Message, attempt int, reason string) error {
+	if dl == nil {
+		return nil
+	}
+
+	headers := make(map[string]string, len(msg.Headers)+4)
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+
+	headers[HeaderDeathReason] = reason
+	headers[HeaderOriginalTopic] = msg.Topic
+	headers[HeaderSubscription] = dl.subscription
+	headers[HeaderDeliveryCount] = strconv.Itoa(attempt)
+
+	return dl.publish(&Message{Topic: dl.topic, Headers: headers, Payload: msg.Payload}, dl.dropped)
+}
+
+func (dl *deadLetter) dropped() {
+	atomic.AddUint64(&dl.stats.deadLettersDropped, 1)
+}
@@ -219,3 +219,71 @@ func ExampleBroker_Subscribe_regex() {
 	// All-3
 	// All2-3
 }
+
+func ExampleBroker_PublishMessage() {
+	broker := NewBroker()
+
+	// Subscribes to any topic that matches "users.*".
+	poller := broker.Subscribe(regexp.MustCompile(`users\..*`))
+
+	broker.Publish("users.india", "User-1")
+	broker.PublishMessage(&Message{
+		Topic:   "users.usa",
+		Headers: map[string]string{"source": "signup"},
+		Payload: "User-2",
+	})
+
+	broker.Close(-1)
+
+	// The message holds the topic, to which the payload was published.
+	for msg, ok := poller.PollMessage(); ok; msg, ok = poller.PollMessage() {
+		fmt.Println(msg.Topic, msg.Headers, msg.Payload)
+	}
+
+	// Output:
+	// users.india map[] User-1
+	// users.usa map[source:signup] User-2
+}
+
+func ExampleBroker_Subscribe_topic() {
+	broker := NewBroker()
+
+	// Subscribes to any topic starting with "users" & ending with "created".
+	poller := broker.Subscribe(MustTopicMatcher("users.#.created"))
+
+	broker.Publish("users.created", "User-1")
+	broker.Publish("users.india.created", "User-2")
+	broker.Publish("users.india.deleted", "User-3")
+
+	broker.Close(-1)
+
+	for val, ok := poller.Poll(); ok; val, ok = poller.Poll() {
+		fmt.Println(val)
+	}
+
+	// Output:
+	// User-1
+	// User-2
+}
+
+func ExampleNewTypedBroker() {
+	broker := NewTypedBroker[int]()
+
+	poller := broker.Subscribe(ExactMatcher("numbers"))
+
+	broker.Publish("numbers", 1)
+	broker.Publish("numbers", 2)
+
+	broker.Close(-1)
+
+	// The polled data are of type int, hence no type assertion is needed.
+	sum := 0
+	for val, ok := poller.Poll(); ok; val, ok = poller.Poll() {
+		sum += val
+	}
+
+	fmt.Println(sum)
+
+	// Output:
+	// 3
+}
@@ -0,0 +1,592 @@
+package gomq
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Filter matches the messages on a predicate expression, similar to JMS message selectors.
+//
+// The expression compares the fields of messages with literals, for example `priority > 3 AND region IN ('eu', 'us')`.
+// A field is looked up in the headers of the message,
+// then in the payload if it is a map with string keys or a struct (or a pointer to one) with exported fields.
+// Fields which aren't valid identifiers, such as "x-region", can be double quoted.
+//
+// The supported operators are:
+//
+//	=, <>, !=, <, <=, >, >=
+//	[NOT] IN ('a', 'b')
+//	IS [NOT] NULL
+//	NOT, AND, OR and parentheses
+//
+// The literals are single quoted strings, numbers, TRUE & FALSE.
+// Header values, being strings, are compared as numbers when compared with a number.
+// Any comparison on a missing field is unknown rather than false, as in JMS & SQL, and NOT of unknown is unknown.
+// Hence `NOT (region = 'eu')` doesn't match a message without region, unless `OR region IS NULL` is added.
+// Any comparison between incomparable values is false.
+// A message matches only if the expression is true.
+// The keywords are case insensitive.
+type Filter struct {
+	expr string
+	root filterNode
+}
+
+// NewFilter creates a Filter for the expression.
+// It returns an error if the expression is invalid.
+func NewFilter(expr string) (*Filter, error) {
+	p := &filterParser{expr: expr}
+	if err := p.scan(); err != nil {
+		return nil, fmt.Errorf("gomq: invalid filter %q: %v", expr, err)
+	}
+
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("gomq: invalid filter %q: %v", expr, err)
+	}
+
+	return &Filter{expr: expr, root: root}, nil
+}
+
+// MustFilter is similar to NewFilter, but panics if the expression is invalid.
+func MustFilter(expr string) *Filter {
+	f, err := NewFilter(expr)
+	if err != nil {
+		panic(err)
+	}
+
+	return f
+}
+
+// String returns the expression of Filter.
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// MatchMessage is the implementation of Filter for MessageMatcher interface.
+// It returns true if the message satisfies the expression.
+func (f *Filter) MatchMessage(msg *Message) bool {
+	return f.root.eval(msg) == truthTrue
+}
+
+// filterTruth is the three-valued result of an expression, where a comparison on a missing field is unknown.
+// Its values are ordered, so that AND is the minimum, OR is the maximum and NOT is the reverse of its operand.
+type filterTruth int
+
+const (
+	truthFalse filterTruth = iota
+	truthUnknown
+	truthTrue
+)
+
+func truthOf(b bool) filterTruth {
+	if b {
+		return truthTrue
+	}
+
+	return truthFalse
+}
+
+type filterNode interface {
+	eval(msg *Message) filterTruth
+}
+
+type andNode struct {
+	left, right filterNode
+}
+
+func (n andNode) eval(msg *Message) filterTruth {
+	left := n.left.eval(msg)
+	if left == truthFalse {
+		return left
+	}
+
+	if right := n.right.eval(msg); right < left {
+		return right
+	}
+
+	return left
+}
+
+type orNode struct {
+	left, right filterNode
+}
+
+func (n orNode) eval(msg *Message) filterTruth {
+	left := n.left.eval(msg)
+	if left == truthTrue {
+		return left
+	}
+
+	if right := n.right.eval(msg); right > left {
+		return right
+	}
+
+	return left
+}
+
+type notNode struct {
+	node filterNode
+}
+
+func (n notNode) eval(msg *Message) filterTruth {
+	return truthTrue - n.node.eval(msg)
+}
+
+type compareNode struct {
+	op          string
+	left, right filterOperand
+}
+
+func (n compareNode) eval(msg *Message) filterTruth {
+	left, right := n.left.value(msg), n.right.value(msg)
+	if left == nil || right == nil {
+		return truthUnknown
+	}
+
+	return truthOf(compareValues(n.op, left, right))
+}
+
+type inNode struct {
+	operand filterOperand
+	list    []filterOperand
+	not     bool
+}
+
+func (n inNode) eval(msg *Message) filterTruth {
+	val := n.operand.value(msg)
+	if val == nil {
+		return truthUnknown
+	}
+
+	for _, item := range n.list {
+		if compareValues("=", val, item.value(msg)) {
+			return truthOf(!n.not)
+		}
+	}
+
+	return truthOf(n.not)
+}
+
+type nullNode struct {
+	operand filterOperand
+	not     bool
+}
+
+func (n nullNode) eval(msg *Message) filterTruth {
+	return truthOf((n.operand.value(msg) == nil) != n.not)
+}
+
+// filterOperand is either a field or a literal.
+// Its value is a float64, string, bool or nil if missing.
+type filterOperand interface {
+	value(msg *Message) interface{}
+}
+
+type filterLiteral struct {
+	val interface{}
+}
+
+func (l filterLiteral) value(*Message) interface{} {
+	return l.val
+}
+
+type filterField struct {
+	name string
+}
+
+func (f filterField) value(msg *Message) interface{} {
+	if val, ok := msg.Headers[f.name]; ok {
+		return val
+	}
+
+	v := reflect.ValueOf(msg.Payload)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		return fieldValue(v.MapIndex(reflect.ValueOf(f.name).Convert(v.Type().Key())))
+	case reflect.Struct:
+		sf, ok := v.Type().FieldByName(f.name)
+		if !ok || sf.PkgPath != "" {
+			return nil
+		}
+
+		// A field promoted through a nil embedded pointer is missing, rather than panicking the publisher.
+		field, err := v.FieldByIndexErr(sf.Index)
+		if err != nil {
+			return nil
+		}
+		return fieldValue(field)
+	}
+
+	return nil
+}
+
+// fieldValue converts the value of a payload field to an operand value.
+func fieldValue(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	}
+
+	return nil
+}
+
+// compareValues returns the result of comparing left with right by op.
+func compareValues(op string, left, right interface{}) bool {
+	left, right = coerce(left, right), coerce(right, left)
+	if left == nil || right == nil {
+		return false
+	}
+
+	var c int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false
+		}
+		if l < r {
+			c = -1
+		} else if l > r {
+			c = 1
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false
+		}
+		c = strings.Compare(l, r)
+	case bool:
+		r, ok := right.(bool)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "=":
+			return l == r
+		case "<>", "!=":
+			return l != r
+		}
+		return false
+	}
+
+	switch op {
+	case "=":
+		return c == 0
+	case "<>", "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+
+	return false
+}
+
+// coerce converts a string to the type of other, as the header values are always strings.
+// It returns nil, if the string can't be converted.
+func coerce(val, other interface{}) interface{} {
+	s, ok := val.(string)
+	if !ok {
+		return val
+	}
+
+	switch other.(type) {
+	case float64:
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+		return nil
+	case bool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+		return nil
+	}
+
+	return val
+}
+
+type filterTokenKind int
+
+const (
+	tokenIdent filterTokenKind = iota
+	tokenKeyword
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenPunct
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+}
+
+var filterKeywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "IN": true, "IS": true, "NULL": true, "TRUE": true, "FALSE": true,
+}
+
+// filterParser is a recursive descent parser for the filter expressions.
+type filterParser struct {
+	expr   string
+	tokens []filterToken
+	pos    int
+}
+
+// scan splits the expression into tokens.
+func (p *filterParser) scan() error {
+	expr := p.expr
+
+	for i := 0; i < len(expr); {
+		c := expr[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')' || c == ',':
+			p.tokens = append(p.tokens, filterToken{kind: tokenPunct, text: expr[i : i+1]})
+			i++
+		case c == '=' || c == '<' || c == '>' || c == '!':
+			j := i + 1
+			if j < len(expr) && (expr[j] == '=' || (c == '<' && expr[j] == '>')) {
+				j++
+			}
+			// "==" isn't a valid operator, as equality is "=".
+			if op := expr[i:j]; op == "!" || op == "==" {
+				return fmt.Errorf("unexpected %q", op)
+			}
+			p.tokens = append(p.tokens, filterToken{kind: tokenOperator, text: expr[i:j]})
+			i = j
+		case c == '\'' || c == '"':
+			// A quote within the text is escaped by doubling it.
+			var text strings.Builder
+			j := i + 1
+			for ; j < len(expr); j++ {
+				if expr[j] == c {
+					if j+1 < len(expr) && expr[j+1] == c {
+						j++
+					} else {
+						break
+					}
+				}
+				text.WriteByte(expr[j])
+			}
+			if j == len(expr) {
+				return fmt.Errorf("unterminated %c", c)
+			}
+
+			kind := tokenString
+			if c == '"' {
+				kind = tokenIdent
+			}
+			p.tokens = append(p.tokens, filterToken{kind: kind, text: text.String()})
+			i = j + 1
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(expr) && (expr[j] == '.' || (expr[j] >= '0' && expr[j] <= '9')) {
+				j++
+			}
+			if _, err := strconv.ParseFloat(expr[i:j], 64); err != nil {
+				return fmt.Errorf("invalid number %q", expr[i:j])
+			}
+			p.tokens = append(p.tokens, filterToken{kind: tokenNumber, text: expr[i:j]})
+			i = j
+		case isIdentByte(c) && !(c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(expr) && (isIdentByte(expr[j]) || expr[j] == '.') {
+				j++
+			}
+			if word := strings.ToUpper(expr[i:j]); filterKeywords[word] {
+				p.tokens = append(p.tokens, filterToken{kind: tokenKeyword, text: word})
+			} else {
+				p.tokens = append(p.tokens, filterToken{kind: tokenIdent, text: expr[i:j]})
+			}
+			i = j
+		default:
+			return fmt.Errorf("unexpected %q", c)
+		}
+	}
+
+	return nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// peek returns true if the next token is of the kind and text.
+func (p *filterParser) peek(kind filterTokenKind, text string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == kind && p.tokens[p.pos].text == text
+}
+
+// accept consumes the next token, if it is of the kind and text.
+func (p *filterParser) accept(kind filterTokenKind, text string) bool {
+	if p.peek(kind, text) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *filterParser) expect(kind filterTokenKind, text string) error {
+	if !p.accept(kind, text) {
+		return p.unexpected(text)
+	}
+
+	return nil
+}
+
+func (p *filterParser) unexpected(expected string) error {
+	if p.pos < len(p.tokens) {
+		return fmt.Errorf("expected %s, found %q", expected, p.tokens[p.pos].text)
+	}
+
+	return fmt.Errorf("expected %s, found end of expression", expected)
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.accept(tokenKeyword, "OR") {
+		var right filterNode
+		if right, err = p.parseAnd(); err == nil {
+			left = orNode{left: left, right: right}
+		}
+	}
+
+	return left, err
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseNot()
+	for err == nil && p.accept(tokenKeyword, "AND") {
+		var right filterNode
+		if right, err = p.parseNot(); err == nil {
+			left = andNode{left: left, right: right}
+		}
+	}
+
+	return left, err
+}
+
+func (p *filterParser) parseNot() (filterNode, error) {
+	if p.accept(tokenKeyword, "NOT") {
+		node, err := p.parseNot()
+		return notNode{node: node}, err
+	}
+
+	if p.accept(tokenPunct, "(") {
+		node, err := p.parseOr()
+		if err == nil {
+			err = p.expect(tokenPunct, ")")
+		}
+		return node, err
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.accept(tokenKeyword, "IS") {
+		not := p.accept(tokenKeyword, "NOT")
+		return nullNode{operand: left, not: not}, p.expect(tokenKeyword, "NULL")
+	}
+
+	not := p.accept(tokenKeyword, "NOT")
+	if not || p.peek(tokenKeyword, "IN") {
+		if err := p.expect(tokenKeyword, "IN"); err != nil {
+			return nil, err
+		}
+		list, err := p.parseList()
+		return inNode{operand: left, list: list, not: not}, err
+	}
+
+	if p.pos == len(p.tokens) || p.tokens[p.pos].kind != tokenOperator {
+		return nil, p.unexpected("an operator")
+	}
+
+	op := p.tokens[p.pos].text
+	p.pos++
+
+	right, err := p.parseOperand()
+	return compareNode{op: op, left: left, right: right}, err
+}
+
+func (p *filterParser) parseList() ([]filterOperand, error) {
+	if err := p.expect(tokenPunct, "("); err != nil {
+		return nil, err
+	}
+
+	list := []filterOperand{}
+	for {
+		item, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+
+		if !p.accept(tokenPunct, ",") {
+			return list, p.expect(tokenPunct, ")")
+		}
+	}
+}
+
+func (p *filterParser) parseOperand() (filterOperand, error) {
+	if p.pos == len(p.tokens) {
+		return nil, p.unexpected("a field or literal")
+	}
+
+	tok := p.tokens[p.pos]
+	switch {
+	case tok.kind == tokenIdent:
+		p.pos++
+		return filterField{name: tok.text}, nil
+	case tok.kind == tokenString:
+		p.pos++
+		return filterLiteral{val: tok.text}, nil
+	case tok.kind == tokenNumber:
+		p.pos++
+		n, _ := strconv.ParseFloat(tok.text, 64)
+		return filterLiteral{val: n}, nil
+	case tok.kind == tokenKeyword && (tok.text == "TRUE" || tok.text == "FALSE"):
+		p.pos++
+		return filterLiteral{val: tok.text == "TRUE"}, nil
+	}
+
+	return nil, p.unexpected("a field or literal")
+}
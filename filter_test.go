@@ -0,0 +1,96 @@
+package gomq
+
+import "testing"
+
+func TestFilter(t *testing.T) {
+	type order struct {
+		Amount   int
+		Region   string
+		Priority *int
+		internal string
+	}
+
+	type details struct {
+		Level int
+	}
+
+	type alert struct {
+		*details
+	}
+
+	priority := 5
+
+	headers := map[string]string{"priority": "5", "region": "eu", "x-source": "web", "express": "true"}
+
+	tests := []struct {
+		expr    string
+		payload interface{}
+		match   bool
+	}{
+		{expr: "priority > 3", match: true},
+		{expr: "priority > 5", match: false},
+		{expr: "priority >= 5 AND priority <= 5", match: true},
+		{expr: "priority = 5.0", match: true},
+		{expr: "priority = 5", match: true},
+		{expr: "priority <> 5", match: false},
+		{expr: "priority != 4", match: true},
+		{expr: "region = 'eu'", match: true},
+		{expr: "region IN ('eu', 'us')", match: true},
+		{expr: "region NOT IN ('eu', 'us')", match: false},
+		{expr: "priority > 3 AND region IN ('eu','us')", match: true},
+		{expr: "priority > 6 OR region = 'eu'", match: true},
+		{expr: "NOT (priority > 6 OR region = 'eu')", match: false},
+		{expr: "priority > 3 and not region = 'us'", match: true},
+		{expr: `"x-source" = 'web'`, match: true},
+		{expr: "express = TRUE", match: true},
+		{expr: "region > 3", match: false},
+		{expr: "missing = 1", match: false},
+		{expr: "missing IS NULL", match: true},
+		{expr: "region IS NOT NULL", match: true},
+		{expr: "missing IN ('a')", match: false},
+		{expr: "missing NOT IN ('a')", match: false},
+		{expr: "NOT missing > 3", match: false},
+		{expr: "NOT (missing = 'eu')", match: false},
+		{expr: "NOT (missing = 'eu') OR missing IS NULL", match: true},
+		{expr: "NOT (missing = 1 AND region = 'us')", match: true},
+		{expr: "NOT (missing = 1 OR region = 'us')", match: false},
+		{expr: "missing = 1 OR region = 'eu'", match: true},
+		{expr: "NOT region > 3", match: true},
+		{expr: "region = 'it''s'", match: false},
+		{expr: "amount > 100", payload: map[string]interface{}{"amount": 150}, match: true},
+		{expr: "amount > 100", payload: map[string]int{"amount": 50}, match: false},
+		{expr: "Amount > 100 AND Region = 'eu'", payload: order{Amount: 150, Region: "eu"}, match: true},
+		{expr: "Amount > 100", payload: &order{Amount: 150}, match: true},
+		{expr: "Priority = 5", payload: order{Priority: &priority}, match: true},
+		{expr: "Priority IS NULL", payload: order{}, match: true},
+		{expr: "internal IS NULL", payload: order{internal: "secret"}, match: true},
+		{expr: "amount IS NULL", payload: 100, match: true},
+		{expr: "Level > 3", payload: alert{details: &details{Level: 4}}, match: true},
+		{expr: "Level > 3", payload: alert{}, match: false},
+		{expr: "Level IS NULL", payload: &alert{}, match: true},
+	}
+
+	for _, test := range tests {
+		msg := &Message{Headers: headers, Payload: test.payload}
+		if match := MustFilter(test.expr).MatchMessage(msg); match != test.match {
+			t.Errorf("Invalid Match for %q on %v: Expected: %v Obtained: %v", test.expr, test.payload, test.match, match)
+		}
+	}
+}
+
+func TestFilterValidation(t *testing.T) {
+	exprs := []string{
+		"", "priority", "priority >", "priority ! 3", "priority == 5", "(priority > 3", "priority > 3)", "region IN 'eu'",
+		"region IN ('eu',)", "region = 'eu", "priority > 3 AND", "priority IS 3", "region NOT 'eu'", "priority > -", "a = b @ c",
+	}
+
+	for _, expr := range exprs {
+		if _, err := NewFilter(expr); err == nil {
+			t.Errorf("Invalid expression %q should return an error", expr)
+		}
+	}
+
+	if f, err := NewFilter("priority > 3"); err != nil || f.String() != "priority > 3" {
+		t.Errorf("Invalid Filter: Expected: priority > 3 <nil> Obtained: %v %v", f, err)
+	}
+}
@@ -1,6 +1,10 @@
 package gomq
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/RohanPoojary/gomq/queue"
@@ -16,6 +20,70 @@ type Poller interface {
 	// If the resource is closed, then Poll will return,
 	// nil and False
 	Poll() (interface{}, bool)
+
+	// PollContext is similar to Poll, but returns once ctx is done.
+	//
+	// If ctx is cancelled or its deadline is exceeded, then ctx.Err() is returned.
+	// If the resource is closed, then ErrClosed is returned.
+	PollContext(ctx context.Context) (interface{}, error)
+
+	// TryPoll is the non blocking variant of Poll.
+	// If data is available, then it returns the data and ok as True.
+	//
+	// If there is no consumable data, then it returns immediately with ok as False.
+	// If the resource is closed, then closed will be True.
+	TryPoll() (data interface{}, ok bool, closed bool)
+
+	// PollTimeout is similar to Poll, but waits at most for timeOut.
+	//
+	// If no data is available within timeOut, then ErrTimeout is returned.
+	// If the resource is closed, then ErrClosed is returned.
+	PollTimeout(timeOut time.Duration) (interface{}, error)
+
+	// PollBatch reads up to max data at once.
+	// This is blocking call until there is consumable data,
+	// later data are gathered until either max data are read or wait elapses.
+	//
+	// If the resource is closed, then PollBatch will return,
+	// nil and False
+	PollBatch(max int, wait time.Duration) ([]interface{}, bool)
+}
+
+var (
+	// ErrClosed is returned while polling, when the resource is closed and has no pending data.
+	ErrClosed = queue.ErrClosed
+
+	// ErrTimeout is returned by PollTimeout, when no data is available within the time out.
+	ErrTimeout = queue.ErrTimeout
+
+	// ErrBrokerClosed is returned while publishing, when the broker is closed.
+	ErrBrokerClosed = errors.New("gomq: broker closed")
+
+	// ErrQueueFull is returned while publishing, when a bounded subscription with queue.Reject policy is full.
+	ErrQueueFull = queue.ErrFull
+
+	// ErrInvalidMessage is returned by PublishMessageE, when the message is nil.
+	ErrInvalidMessage = errors.New("gomq: invalid message")
+)
+
+// PublishError is returned by PublishContext, when some of the matched subscriptions couldn't be served.
+type PublishError struct {
+
+	// Subscriptions holds the names of the subscriptions, which couldn't be served.
+	Subscriptions []string
+
+	// Err is the cause, such as context.DeadlineExceeded or ErrQueueFull.
+	// If the subscriptions failed for different causes, it is the first of them.
+	Err error
+}
+
+func (e *PublishError) Error() string {
+	return fmt.Sprintf("gomq: couldn't publish to subscriptions %s: %v", strings.Join(e.Subscriptions, ", "), e.Err)
+}
+
+// Unwrap returns the cause of the error.
+func (e *PublishError) Unwrap() error {
+	return e.Err
 }
 
 // Broker represents the Broker for interaction.
@@ -23,17 +91,74 @@ type Broker interface {
 
 	// Publish publishes the `data` to the topic.
 	// It returns the count of matched subscribers to which the `data` has been published.
+	// Subscribers rejecting the `data` as they are full, are not counted.
 	//
 	// For AsnycBroker, it returns the count of matched subscribers during invocation,
 	// this can get increased during actual delivery.
 	Publish(topic string, data interface{}) int
 
-	// Subscribe creates a Poller which polls data
+	// PublishMessage is similar to Publish, but publishes the whole message to its topic.
+	// The ID & Timestamp of the message are set by the broker, if they are empty.
+	PublishMessage(msg *Message) int
+
+	// PublishE is similar to Publish, but returns an error if the `data` couldn't be published.
+	//
+	// ErrBrokerClosed is returned once the broker is closed, in which case Publish discards the `data`.
+	// For a broker with a Registry, an error wrapping ErrTypeMismatch is returned if the type of `data`
+	// isn't the type registered for the topic.
+	// ErrQueueFull is returned along with the count, if any of the matched subscribers rejected the `data` as it is full.
+	// For AsyncBroker, ErrQueueFull is not returned as the `data` is delivered after the invocation.
+	PublishE(topic string, data interface{}) (int, error)
+
+	// PublishMessageE is similar to PublishE, but publishes the whole message to its topic.
+	PublishMessageE(msg *Message) (int, error)
+
+	// PublishContext is similar to PublishE, but waits for space in the full subscriptions until ctx is done.
+	// Hence, the publisher is slowed down to the pace of the subscriptions bounded by WithCapacity.
+	// The subscriptions with queue.DropNewest, queue.DropOldest or queue.Reject policy follow their policy instead.
+	//
+	// If any of the matched subscriptions couldn't be served, a *PublishError naming them is returned along with the count.
+	// For AsyncBroker, it waits until the `data` is published to the subscriptions, after the data published earlier.
+	PublishContext(ctx context.Context, topic string, data interface{}) (int, error)
+
+	// PublishMessageContext is similar to PublishContext, but publishes the whole message to its topic.
+	PublishMessageContext(ctx context.Context, msg *Message) (int, error)
+
+	// PublishAt publishes the `data` to the topic at the time, hence it is visible to the subscribers only then.
+	// The `data` is published to the subscribers matching at that time, and the data due at the same time are published in order.
+	// The `data` doesn't wait for space in full subscriptions, hence they don't receive it regardless of their overflow policy.
+	//
+	// It returns ErrBrokerClosed once the broker is closed, the `data` yet to be published on closing is discarded.
+	PublishAt(topic string, data interface{}, at time.Time) error
+
+	// PublishAfter is similar to PublishAt, but publishes the `data` after the delay.
+	PublishAfter(topic string, data interface{}, delay time.Duration) error
+
+	// PublishMessageAt is similar to PublishAt, but publishes the whole message to its topic.
+	// The Timestamp of the message is set on publishing, if it is empty.
+	PublishMessageAt(msg *Message, at time.Time) error
+
+	// Subscribe creates a Subscription which polls data
 	// from matched topics.
-	Subscribe(topic Matcher) Poller
+	// Once the broker is closed, the Subscription is closed as well, hence polling it returns false.
+	Subscribe(topic Matcher) Subscription
+
+	// SubscribeWithOptions is similar to Subscribe,
+	// but the Subscription is configured based on opts.
+	SubscribeWithOptions(topic Matcher, opts ...SubscribeOption) Subscription
+
+	// SubscribeMessage is similar to SubscribeWithOptions,
+	// but the Subscription polls data from the messages matched by matcher, such as a HeaderMatcher.
+	SubscribeMessage(matcher MessageMatcher, opts ...SubscribeOption) Subscription
+
+	// SubscribeGroup adds a member to the consumer group, which polls data from matched topics.
+	// All the members of a group share the same queue, hence the data is delivered to only one of them.
+	//
+	// It is the same as SubscribeWithOptions with WithGroup option.
+	SubscribeGroup(group string, topic Matcher) Subscription
 
 	// Close closes the Broker and renders it read only.
-	// Hence, all data pushed will be ignored.
+	// Hence, all data published afterwards will be ignored, and PublishE returns ErrBrokerClosed.
 	// All the open resources will be collected based on timeOut.
 	//
 	// If timeOut < 0, then resources will be closed once
@@ -46,11 +171,35 @@ type Broker interface {
 // NewBroker creates a new broker for message exchange.
 // A simple broker which synchronously publishes the data to all its matching subscribers.
 func NewBroker() Broker {
-	return &broker{
+	return NewBrokerWithOptions()
+}
+
+// NewBrokerWithOptions is similar to NewBroker, but the broker is configured based on opts.
+func NewBrokerWithOptions(opts ...BrokerOption) Broker {
+	cfg := newBrokerConfig(opts)
+
+	b := &broker{
 		brokerBase: brokerBase{
-			queueMatchers: []queueMatcher{},
+			index:     newSubscriptionIndex(),
+			registry:  cfg.registry,
+			histories: cfg.histories,
 		},
 	}
+
+	// The due messages don't wait for space in full subscriptions, as that would hold up the rest of the due messages.
+	b.scheduler = newScheduler(func(msg *Message) {
+		b.publishContext(doneContext(), b.stamp(msg))
+	})
+
+	b.deadLetters = func(msg *Message, dropped func()) error {
+		_, err := b.publishContextE(doneContext(), msg)
+		if err != nil {
+			dropped()
+		}
+		return err
+	}
+
+	return b
 }
 
 type broker struct {
@@ -58,30 +207,105 @@ type broker struct {
 }
 
 func (b *broker) Publish(topic string, data interface{}) int {
-	b.RLock()
-	defer b.RUnlock()
+	count, _ := b.publishE(&Message{Topic: topic, Payload: data})
+	return count
+}
 
-	count := 0
-	for _, q := range b.queueMatchers {
-		if q.matcher.MatchString(topic) {
-			q.queue.Push(data)
-			count += 1
-		}
+func (b *broker) PublishMessage(msg *Message) int {
+	count, _ := b.PublishMessageE(msg)
+	return count
+}
+
+func (b *broker) PublishE(topic string, data interface{}) (int, error) {
+	return b.publishE(&Message{Topic: topic, Payload: data})
+}
+
+func (b *broker) PublishMessageE(msg *Message) (int, error) {
+	return b.publishE(copyMessage(msg))
+}
+
+// publishE publishes the message owned by the broker.
+func (b *broker) publishE(msg *Message) (int, error) {
+	if err := b.validate(msg); err != nil {
+		return 0, err
 	}
 
-	return count
+	if b.isClosed() {
+		return 0, ErrBrokerClosed
+	}
+
+	return b.brokerBase.publish(b.stamp(msg))
+}
+
+func (b *broker) PublishContext(ctx context.Context, topic string, data interface{}) (int, error) {
+	return b.publishContextE(ctx, &Message{Topic: topic, Payload: data})
+}
+
+func (b *broker) PublishMessageContext(ctx context.Context, msg *Message) (int, error) {
+	return b.publishContextE(ctx, copyMessage(msg))
+}
+
+// publishContextE is similar to publishE, but waits for space in the full subscriptions until ctx is done.
+func (b *broker) publishContextE(ctx context.Context, msg *Message) (int, error) {
+	if err := b.validate(msg); err != nil {
+		return 0, err
+	}
+
+	if b.isClosed() {
+		return 0, ErrBrokerClosed
+	}
+
+	return publishResult(b.brokerBase.publishContext(ctx, b.stamp(msg)))
+}
+
+// publishResult converts the result of publishContext, to that of PublishContext.
+func publishResult(count int, unserved []string, err error) (int, error) {
+	if err != nil {
+		return count, &PublishError{Subscriptions: unserved, Err: err}
+	}
+
+	return count, nil
 }
 
 // NewAsyncBroker creates a new async broker for message exchange.
 // This broker pushes the data to its internal queue which get published to subscribers asynchronously.
 func NewAsyncBroker() Broker {
+	return NewAsyncBrokerWithOptions()
+}
+
+// NewAsyncBrokerWithOptions is similar to NewAsyncBroker, but the broker is configured based on opts.
+func NewAsyncBrokerWithOptions(opts ...BrokerOption) Broker {
+	cfg := newBrokerConfig(opts)
+
 	b := &asyncBroker{
 		queue: queue.NewQueue(),
+		done:  make(chan struct{}),
 		brokerBase: brokerBase{
-			queueMatchers: []queueMatcher{},
+			index:     newSubscriptionIndex(),
+			registry:  cfg.registry,
+			histories: cfg.histories,
 		},
 	}
 
+	// The due messages are queued as requests, hence manage doesn't wait for space in full subscriptions to publish them.
+	b.scheduler = newScheduler(func(msg *Message) {
+		b.push(&publishRequest{ctx: doneContext(), msg: b.stamp(msg), done: func(publishResponse) {}})
+	})
+
+	// The dead letters are queued along with the data published, hence they are published by manage.
+	b.deadLetters = func(msg *Message, dropped func()) error {
+		err := b.request(doneContext(), msg, func(res publishResponse) {
+			if res.err != nil {
+				dropped()
+			}
+		})
+
+		if err != nil {
+			dropped()
+		}
+		return err
+	}
+
 	go b.manage()
 
 	return b
@@ -90,55 +314,180 @@ func NewAsyncBroker() Broker {
 type asyncBroker struct {
 	brokerBase
 	queue queue.Queue
+
+	// done is closed, once all the messages in queue are published.
+	done chan struct{}
+
+	// closeTimeOut is the timeOut of Close, which is used for closing the subscribers once done.
+	closeTimeOut time.Duration
 }
 
-type asyncPayload struct {
-	topic string
-	data  interface{}
+// publishRequest is queued by PublishContext, for waiting until its message is published.
+// Its done is invoked by manage along with the result, unless the request is discarded on closing.
+type publishRequest struct {
+	ctx  context.Context
+	msg  *Message
+	done func(publishResponse)
+}
+
+type publishResponse struct {
+	count int
+	err   error
 }
 
 func (b *asyncBroker) Publish(topic string, data interface{}) int {
+	count, _ := b.publishE(&Message{Topic: topic, Payload: data})
+	return count
+}
+
+func (b *asyncBroker) PublishMessage(msg *Message) int {
+	count, _ := b.PublishMessageE(msg)
+	return count
+}
+
+func (b *asyncBroker) PublishE(topic string, data interface{}) (int, error) {
+	return b.publishE(&Message{Topic: topic, Payload: data})
+}
+
+func (b *asyncBroker) PublishMessageE(msg *Message) (int, error) {
+	return b.publishE(copyMessage(msg))
+}
+
+// publishE queues the message owned by the broker.
+func (b *asyncBroker) publishE(msg *Message) (int, error) {
+	if err := b.validate(msg); err != nil {
+		return 0, err
+	}
+
+	return b.push(b.stamp(msg))
+}
+
+func (b *asyncBroker) PublishContext(ctx context.Context, topic string, data interface{}) (int, error) {
+	return b.publishContextE(ctx, &Message{Topic: topic, Payload: data})
+}
+
+func (b *asyncBroker) PublishMessageContext(ctx context.Context, msg *Message) (int, error) {
+	return b.publishContextE(ctx, copyMessage(msg))
+}
+
+// publishContextE queues the message owned by the broker, and waits until it is published.
+func (b *asyncBroker) publishContextE(ctx context.Context, msg *Message) (int, error) {
+	result := make(chan publishResponse, 1)
+	if err := b.request(ctx, msg, func(res publishResponse) { result <- res }); err != nil {
+		return 0, err
+	}
+
+	// The request is queued, hence it is published in order with the data published earlier.
+	select {
+	case res := <-result:
+		return res.count, res.err
+	case <-b.done:
+	}
+
+	// The request might have been published just before done, else it was discarded on closing.
+	select {
+	case res := <-result:
+		return res.count, res.err
+	default:
+		return 0, ErrBrokerClosed
+	}
+}
+
+// request queues the message owned by the broker, to be published until ctx is done.
+// The done is invoked once it is published, without waiting for it.
+func (b *asyncBroker) request(ctx context.Context, msg *Message, done func(publishResponse)) error {
+	if err := b.validate(msg); err != nil {
+		return err
+	}
+
+	_, err := b.push(&publishRequest{ctx: ctx, msg: b.stamp(msg), done: done})
+	return err
+}
+
+// push queues the value to be published, unless the broker is closed.
+// It returns the count of subscribers during invocation.
+func (b *asyncBroker) push(val interface{}) (int, error) {
 	b.RLock()
 	defer b.RUnlock()
 
-	minMatchCount := len(b.queueMatchers)
+	// Close waits for the lock before closing the queue, hence the queue is open unless closed is set.
+	if b.closed {
+		return 0, ErrBrokerClosed
+	}
+
+	minMatchCount := len(b.subscriptions)
 
-	b.queue.Push(asyncPayload{topic: topic, data: data})
-	return minMatchCount
+	if err := b.queue.Push(val); err != nil {
+		return 0, err
+	}
+
+	return minMatchCount, nil
 }
 
 func (b *asyncBroker) manage() {
 	for {
 		val, ok := b.queue.Poll()
 		if !ok {
-			return
+			break
 		}
 
-		payload := val.(asyncPayload)
-		b.publish(payload.topic, payload.data)
+		switch val := val.(type) {
+		case *Message:
+			b.publish(val)
+		case *publishRequest:
+			count, err := publishResult(b.publishContext(val.ctx, val.msg))
+			val.done(publishResponse{count: count, err: err})
+		}
 	}
-}
 
-func (b *asyncBroker) publish(topic string, data interface{}) int {
-	b.RLock()
-	defer b.RUnlock()
+	close(b.done)
 
-	count := 0
-	for _, q := range b.queueMatchers {
-		if q.matcher.MatchString(topic) {
-			q.queue.Push(data)
-			count += 1
-		}
-	}
+	b.Lock()
+	defer b.Unlock()
 
-	return count
+	// For a timeOut >= 0, the subscribers are closed by Close.
+	if b.closeTimeOut < 0 {
+		b.unsafeClose(b.closeTimeOut)
+	}
 }
 
 func (b *asyncBroker) Close(timeOut time.Duration) {
+	b.Lock()
+	if b.closed {
+		b.Unlock()
+		return
+	}
+
+	b.closed = true
+	b.closeTimeOut = timeOut
+	b.Unlock()
+
+	b.scheduler.stop()
+
+	// The lock is not held while closing the queue, so that the pending messages are published meanwhile.
+	// For a negative timeOut, the subscribers are closed by manage once the pending messages are published.
+	deadline := time.Now().Add(timeOut)
+	b.queue.Close(timeOut)
+	if timeOut < 0 {
+		return
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	// A message might be still being published, unless it's blocked on a full subscriber past the timeOut.
+	select {
+	case <-b.done:
+	case <-timer.C:
+	}
+
+	remaining := time.Until(deadline)
+	if remaining < 0 {
+		remaining = 0
+	}
 
 	b.Lock()
 	defer b.Unlock()
 
-	b.queue.Close(timeOut)
-	b.brokerBase.unsafeClose(timeOut)
+	b.unsafeClose(remaining)
 }
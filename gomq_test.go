@@ -1,12 +1,17 @@
 package gomq
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"runtime"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/RohanPoojary/gomq/queue"
 )
 
 func TestBrokerFanoutPattern(t *testing.T) {
@@ -269,3 +274,1265 @@ func TestRoutineLeaks(t *testing.T) {
 		testRoutineLeaks(t, NewAsyncBroker())
 	})
 }
+
+func TestBrokerUnsubscribe(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerUnsubscribe(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerUnsubscribe(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerUnsubscribe(t *testing.T, broker Broker) {
+	defer broker.Close(-1)
+
+	kept := broker.Subscribe(ExactMatcher("all"))
+	removed := broker.Subscribe(ExactMatcher("all"))
+
+	broker.Publish("all", "record-1")
+
+	if val, ok := removed.Poll(); !ok || val != "record-1" {
+		t.Errorf("Expected Value: record-1, Obtained: %v %v", val, ok)
+	}
+
+	removed.Unsubscribe(-1)
+
+	if _, ok := removed.Poll(); ok {
+		t.Error("Poll after Unsubscribe should be False")
+	}
+
+	if count := broker.Publish("all", "record-2"); count != 1 {
+		t.Errorf("Invalid Publish Count: Expected: 1 Obtained: %d", count)
+	}
+
+	for _, expected := range []string{"record-1", "record-2"} {
+		if val, ok := kept.Poll(); !ok || val != expected {
+			t.Errorf("Expected Value: %s, Obtained: %v %v", expected, val, ok)
+		}
+	}
+
+	// Unsubscribing twice should be safe.
+	removed.Unsubscribe(0)
+}
+
+func TestUnsubscribeRoutineLeaks(t *testing.T) {
+	broker := NewBroker()
+	defer broker.Close(0)
+
+	expected := runtime.NumGoroutine()
+
+	for i := 0; i < 10; i++ {
+		sub := broker.Subscribe(ExactMatcher("all"))
+		broker.Publish("all", i)
+		sub.Unsubscribe(0)
+	}
+
+	time.Sleep(10 * time.Millisecond) // Give some time to golang to cleanup routines
+
+	if current := runtime.NumGoroutine(); expected < current {
+		t.Errorf("Invalid Go Routine Count: Expected: %d Obtained: %d", expected, current)
+	}
+}
+
+func TestBrokerPollContext(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerPollContext(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerPollContext(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerPollContext(t *testing.T, broker Broker) {
+	sub := broker.Subscribe(ExactMatcher("all"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := sub.PollContext(ctx); err != context.Canceled {
+		t.Errorf("Invalid Error: Expected: %v, Obtained: %v", context.Canceled, err)
+	}
+
+	broker.Publish("all", "record-1")
+
+	if val, err := sub.PollContext(context.Background()); err != nil || val != "record-1" {
+		t.Errorf("Invalid Poll: Expected: record-1 <nil>, Obtained: %v %v", val, err)
+	}
+
+	broker.Close(-1)
+
+	if _, err := sub.PollContext(context.Background()); err != ErrClosed {
+		t.Errorf("Invalid Error: Expected: %v, Obtained: %v", ErrClosed, err)
+	}
+}
+
+func TestBrokerTryPoll(t *testing.T) {
+	broker := NewBroker()
+	sub := broker.Subscribe(ExactMatcher("all"))
+
+	if _, ok, closed := sub.TryPoll(); ok || closed {
+		t.Errorf("TryPoll on empty subscription: Expected: false false, Obtained: %v %v", ok, closed)
+	}
+
+	broker.Publish("all", "record-1")
+
+	if val, ok, _ := sub.TryPoll(); !ok || val != "record-1" {
+		t.Errorf("Invalid TryPoll: Expected: record-1 true, Obtained: %v %v", val, ok)
+	}
+
+	if _, err := sub.PollTimeout(10 * time.Millisecond); err != ErrTimeout {
+		t.Errorf("Invalid Error: Expected: %v, Obtained: %v", ErrTimeout, err)
+	}
+
+	broker.Close(-1)
+
+	if _, ok, closed := sub.TryPoll(); ok || !closed {
+		t.Errorf("TryPoll on closed subscription: Expected: false true, Obtained: %v %v", ok, closed)
+	}
+}
+
+func TestBrokerPollBatch(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerPollBatch(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerPollBatch(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerPollBatch(t *testing.T, broker Broker) {
+	sub := broker.Subscribe(ExactMatcher("all"))
+
+	maxCount := 10
+	for i := 0; i < maxCount; i++ {
+		broker.Publish("all", i)
+	}
+
+	expected := 0
+	for expected < maxCount {
+		values, ok := sub.PollBatch(maxCount, 10*time.Millisecond)
+		if !ok {
+			t.Fatal("PollBatch on available data should be True Got False")
+		}
+
+		for _, val := range values {
+			if val != expected {
+				t.Errorf("Invalid Value: Expected: %d Obtained: %v", expected, val)
+			}
+			expected++
+		}
+	}
+
+	broker.Close(-1)
+
+	if _, ok := sub.PollBatch(maxCount, 0); ok {
+		t.Error("PollBatch should be False")
+	}
+}
+
+func TestBrokerBoundedSubscription(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerBoundedSubscription(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerBoundedSubscription(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerBoundedSubscription(t *testing.T, broker Broker) {
+	bounded := broker.SubscribeWithOptions(ExactMatcher("all"), WithCapacity(2), WithOverflowPolicy(queue.DropOldest))
+	unbounded := broker.Subscribe(ExactMatcher("all"))
+
+	maxCount := 10
+	for i := 0; i < maxCount; i++ {
+		broker.Publish("all", i)
+	}
+
+	// Ensures all the data has been delivered.
+	for i := 0; i < maxCount; i++ {
+		unbounded.Poll()
+	}
+
+	broker.Close(-1)
+
+	values := []interface{}{}
+	for val, ok := bounded.Poll(); ok; val, ok = bounded.Poll() {
+		values = append(values, val)
+	}
+
+	if fmt.Sprint(values) != "[8 9]" {
+		t.Errorf("Invalid Values: Expected: [8 9], Obtained: %v", values)
+	}
+}
+
+func TestBrokerCloseWithBlockedPublish(t *testing.T) {
+	broker := NewBroker()
+	broker.SubscribeWithOptions(ExactMatcher("all"), WithCapacity(1), WithOverflowPolicy(queue.Block))
+
+	broker.Publish("all", 1)
+
+	published := make(chan int)
+	go func() {
+		published <- broker.Publish("all", 2)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	// Close shouldn't wait for the publisher blocked on a full subscription.
+	broker.Close(0)
+
+	if count := <-published; count != 0 {
+		t.Errorf("Invalid Publish Count: Expected: 0 Obtained: %d", count)
+	}
+}
+
+func TestBrokerSubscriptionName(t *testing.T) {
+	broker := NewBroker()
+	defer broker.Close(-1)
+
+	named := broker.SubscribeWithOptions(ExactMatcher("all"), WithName("users"))
+	first := broker.Subscribe(ExactMatcher("all"))
+	second := broker.Subscribe(ExactMatcher("all"))
+
+	if named.Name() != "users" {
+		t.Errorf("Invalid Name: Expected: users Obtained: %s", named.Name())
+	}
+
+	if first.Name() == "" || first.Name() == second.Name() {
+		t.Errorf("Generated names should be unique: Obtained: %q %q", first.Name(), second.Name())
+	}
+}
+
+func TestBrokerDurableSubscription(t *testing.T) {
+	broker := NewBroker()
+	defer broker.Close(-1)
+
+	sub := broker.SubscribeWithOptions(ExactMatcher("all"), WithName("users"), WithDurable())
+	broker.Publish("all", "record-1")
+
+	// Attaches to the existing subscription, rather than creating a new one.
+	resumed := broker.SubscribeWithOptions(ExactMatcher("all"), WithName("users"), WithDurable())
+	if count := broker.Publish("all", "record-2"); count != 1 {
+		t.Errorf("Invalid Publish Count: Expected: 1 Obtained: %d", count)
+	}
+
+	for _, expected := range []string{"record-1", "record-2"} {
+		if val, ok := resumed.Poll(); !ok || val != expected {
+			t.Errorf("Expected Value: %s, Obtained: %v %v", expected, val, ok)
+		}
+	}
+
+	sub.Unsubscribe(-1)
+
+	if _, ok := resumed.Poll(); ok {
+		t.Error("Poll after Unsubscribe should be False")
+	}
+
+	// Non durable subscriptions with the same name are independent.
+	broker.SubscribeWithOptions(ExactMatcher("all"), WithName("orders"))
+	broker.SubscribeWithOptions(ExactMatcher("all"), WithName("orders"))
+	if count := broker.Publish("all", "record-3"); count != 2 {
+		t.Errorf("Invalid Publish Count: Expected: 2 Obtained: %d", count)
+	}
+}
+
+func TestBrokerDurableDetach(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerDurableDetach(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerDurableDetach(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerDurableDetach(t *testing.T, broker Broker) {
+	defer broker.Close(-1)
+
+	subscribe := func() Subscription {
+		return broker.SubscribeWithOptions(ExactMatcher("all"), WithName("users"), WithDurable())
+	}
+
+	sub := subscribe()
+	broker.PublishContext(context.Background(), "all", "record-1")
+	sub.Detach()
+
+	// The data published while detached is held by the subscription.
+	broker.PublishContext(context.Background(), "all", "record-2")
+
+	resumed := subscribe()
+	if values, ok := resumed.PollBatch(2, 10*time.Millisecond); !ok || fmt.Sprint(values) != "[record-1 record-2]" {
+		t.Errorf("Invalid Values: Expected: [record-1 record-2] Obtained: %v %v", values, ok)
+	}
+
+	resumed.Unsubscribe(-1)
+	broker.PublishContext(context.Background(), "all", "record-3")
+
+	// The unsubscribed subscription is removed, hence subscribing again creates a new one.
+	if val, ok, _ := subscribe().TryPoll(); ok {
+		t.Errorf("Data published after Unsubscribe should not be held: Obtained: %v", val)
+	}
+
+	// A subscription which isn't durable is unsubscribed on detaching.
+	orders := broker.Subscribe(ExactMatcher("orders"))
+	orders.Detach()
+
+	if count, _ := broker.PublishContext(context.Background(), "orders", "order-1"); count != 0 {
+		t.Errorf("Invalid Publish Count: Expected: 0 Obtained: %d", count)
+	}
+}
+
+func TestBrokerPublishMessage(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerPublishMessage(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerPublishMessage(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerPublishMessage(t *testing.T, broker Broker) {
+	defer broker.Close(-1)
+
+	sub := broker.Subscribe(regexp.MustCompile(`users\..*`))
+
+	broker.Publish("users.india", "record-1")
+	broker.PublishMessage(&Message{
+		Topic:   "users.usa",
+		Headers: map[string]string{"source": "test"},
+		Payload: "record-2",
+	})
+
+	first, ok := sub.PollMessage()
+	if !ok || first.Topic != "users.india" || first.Payload != "record-1" {
+		t.Errorf("Invalid Message: Expected: users.india record-1, Obtained: %+v %v", first, ok)
+	}
+
+	second, err := sub.PollMessageContext(context.Background())
+	if err != nil || second.Topic != "users.usa" || second.Headers["source"] != "test" {
+		t.Errorf("Invalid Message: Expected: users.usa source=test, Obtained: %+v %v", second, err)
+	}
+
+	if first.ID == "" || first.ID == second.ID {
+		t.Errorf("Message IDs should be unique: Obtained: %q %q", first.ID, second.ID)
+	}
+
+	if first.Timestamp.IsZero() || second.Timestamp.Before(first.Timestamp) {
+		t.Errorf("Invalid Timestamps: Obtained: %v %v", first.Timestamp, second.Timestamp)
+	}
+}
+
+func TestBrokerAcknowledgement(t *testing.T) {
+	broker := NewBroker()
+	defer broker.Close(-1)
+
+	sub := broker.SubscribeWithOptions(ExactMatcher("all"), WithAckDeadline(20*time.Millisecond))
+
+	for i := 1; i <= 3; i++ {
+		broker.Publish("all", fmt.Sprintf("record-%d", i))
+	}
+
+	// Acknowledged deliveries are not redelivered.
+	acked, _ := sub.PollDelivery()
+	if err := acked.Ack(); err != nil {
+		t.Errorf("Invalid Error: Expected: <nil>, Obtained: %v", err)
+	}
+	if err := acked.Ack(); err != ErrDeliverySettled {
+		t.Errorf("Invalid Error: Expected: %v, Obtained: %v", ErrDeliverySettled, err)
+	}
+
+	// Unacknowledged delivery is redelivered ahead of the pending messages, once its deadline elapses.
+	expired, _ := sub.PollDelivery()
+	time.Sleep(40 * time.Millisecond)
+
+	redelivered, _ := sub.PollDelivery()
+	if redelivered.Payload != expired.Payload || redelivered.Attempt != 2 {
+		t.Errorf("Invalid Redelivery: Expected: %v 2, Obtained: %v %d", expired.Payload, redelivered.Payload, redelivered.Attempt)
+	}
+	if err := expired.Ack(); err != ErrDeliverySettled {
+		t.Errorf("Invalid Error: Expected: %v, Obtained: %v", ErrDeliverySettled, err)
+	}
+	redelivered.Ack()
+
+	// Nack with requeue redelivers immediately.
+	nacked, _ := sub.PollDelivery()
+	nacked.Nack(true)
+
+	requeued, _ := sub.PollDelivery()
+	if requeued.Payload != "record-3" || requeued.Attempt != 2 {
+		t.Errorf("Invalid Redelivery: Expected: record-3 2, Obtained: %v %d", requeued.Payload, requeued.Attempt)
+	}
+
+	// Nack without requeue discards the message.
+	requeued.Nack(false)
+	time.Sleep(40 * time.Millisecond)
+
+	if _, ok, _ := sub.TryPoll(); ok {
+		t.Error("Discarded message should not be redelivered")
+	}
+}
+
+func TestBrokerDeadLetter(t *testing.T) {
+	broker := NewBroker()
+	defer broker.Close(-1)
+
+	deadLetters := broker.Subscribe(ExactMatcher("dead-letters"))
+	sub := broker.SubscribeWithOptions(ExactMatcher("all"),
+		WithName("users"),
+		WithAckDeadline(10*time.Millisecond),
+		WithMaxDeliveries(2),
+		WithDeadLetter("dead-letters"),
+	)
+
+	broker.PublishMessage(&Message{Topic: "all", Headers: map[string]string{"id": "1"}, Payload: "record-1"})
+	broker.Publish("all", "record-2")
+	broker.Publish("all", "record-3")
+
+	// Rejected message is dead lettered along with the reason.
+	rejected, _ := sub.PollDelivery()
+	rejected.Reject("invalid")
+
+	// Message is dead lettered, once delivered maximum number of times.
+	for i := 0; i < 2; i++ {
+		expired, _ := sub.PollDelivery()
+		expired.Nack(true)
+	}
+
+	// Message not acknowledged without requeue is dead lettered.
+	nacked, _ := sub.PollDelivery()
+	nacked.Nack(false)
+
+	expected := []struct {
+		payload string
+		reason  string
+		count   string
+	}{
+		{payload: "record-1", reason: "invalid", count: "1"},
+		{payload: "record-2", reason: ReasonMaxDeliveries, count: "2"},
+		{payload: "record-3", reason: ReasonRejected, count: "1"},
+	}
+
+	for _, exp := range expected {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		msg, err := deadLetters.PollMessageContext(ctx)
+		cancel()
+		if err != nil {
+			t.Fatalf("Invalid Error: Expected: <nil>, Obtained: %v", err)
+		}
+
+		if msg.Payload != exp.payload || msg.Headers[HeaderDeathReason] != exp.reason || msg.Headers[HeaderDeliveryCount] != exp.count {
+			t.Errorf("Invalid Dead Letter: Expected: %v %s %s, Obtained: %v %v", exp.payload, exp.reason, exp.count, msg.Payload, msg.Headers)
+		}
+
+		if msg.Headers[HeaderOriginalTopic] != "all" || msg.Headers[HeaderSubscription] != "users" {
+			t.Errorf("Invalid Dead Letter Origin: Obtained: %v", msg.Headers)
+		}
+	}
+
+	if _, ok, _ := sub.TryPoll(); ok {
+		t.Error("Dead lettered messages should not be redelivered")
+	}
+}
+
+func TestBrokerDeadLetterFull(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerDeadLetterFull(t, NewBrokerWithOptions(WithRegistry(deadLetterRegistry())))
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerDeadLetterFull(t, NewAsyncBrokerWithOptions(WithRegistry(deadLetterRegistry())))
+	})
+}
+
+// deadLetterRegistry accepts only string payloads on the dead letter topic.
+func deadLetterRegistry() *Registry {
+	registry := NewRegistry()
+	RegisterType[string](registry, ExactMatcher("dead-letters"))
+	return registry
+}
+
+func testBrokerDeadLetterFull(t *testing.T, broker Broker) {
+	defer broker.Close(0)
+
+	deadLetters := broker.SubscribeWithOptions(ExactMatcher("dead-letters"), WithCapacity(1))
+	sub := broker.SubscribeWithOptions(ExactMatcher("all"), WithDeadLetter("dead-letters"))
+
+	for _, data := range []interface{}{"record-1", 2, "record-3"} {
+		broker.PublishContext(context.Background(), "all", data)
+	}
+
+	// The dead letter subscription is full after the first, hence rejecting the rest should not wait for it.
+	errs := make(chan error, 3)
+	go func() {
+		for i := 0; i < 3; i++ {
+			if d, err := sub.PollDeliveryContext(context.Background()); err == nil {
+				errs <- d.Reject("invalid")
+			}
+		}
+	}()
+
+	rejected := []error{}
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-errs:
+			rejected = append(rejected, err)
+		case <-time.After(time.Second):
+			t.Fatal("Reject should not wait for space in the dead letter subscription")
+		}
+	}
+
+	if rejected[0] != nil {
+		t.Errorf("Invalid Reject Error: Expected: <nil> Obtained: %v", rejected[0])
+	}
+
+	// The registry rejects the invalid type while queueing, hence the error is returned by AsyncBroker too.
+	if !errors.Is(rejected[1], ErrTypeMismatch) {
+		t.Errorf("Invalid Reject Error: Expected: %v Obtained: %v", ErrTypeMismatch, rejected[1])
+	}
+
+	// The dead letters dropped are counted, though AsyncBroker drops them after Reject returns.
+	deadline := time.Now().Add(time.Second)
+	for sub.Stats().DeadLettersDropped != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if dropped := sub.Stats().DeadLettersDropped; dropped != 2 {
+		t.Errorf("Invalid Dropped Dead Letters: Expected: 2 Obtained: %d", dropped)
+	}
+
+	if val, err := deadLetters.PollTimeout(time.Second); err != nil || val != "record-1" {
+		t.Errorf("Invalid Dead Letter: Expected: record-1 Obtained: %v %v", val, err)
+	}
+
+	// The payload not of the type registered for the dead letter topic is not dead lettered.
+	for {
+		msg, err := deadLetters.PollTimeout(50 * time.Millisecond)
+		if err != nil {
+			break
+		}
+		if msg == 2 {
+			t.Errorf("Dead letter of invalid type should be rejected by the registry: Obtained: %v", msg)
+		}
+	}
+}
+
+func TestBrokerConsumerGroup(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerConsumerGroup(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerConsumerGroup(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerConsumerGroup(t *testing.T, broker Broker) {
+	memberCount := 3
+	members := []Subscription{}
+	for i := 0; i < memberCount; i++ {
+		members = append(members, broker.SubscribeGroup("workers", ExactMatcher("jobs")))
+	}
+
+	if count := broker.Publish("jobs", 0); count != 1 {
+		t.Errorf("Invalid Publish Count: Expected: 1 Obtained: %d", count)
+	}
+
+	// The group is retained until all its members have unsubscribed.
+	members[0].Unsubscribe(-1)
+	members = members[1:]
+
+	maxCount := 100
+	for i := 1; i < maxCount; i++ {
+		broker.Publish("jobs", i)
+	}
+
+	wg := sync.WaitGroup{}
+	received := make([]int32, maxCount)
+	for _, member := range members {
+		wg.Add(1)
+		go func(member Subscription) {
+			defer wg.Done()
+			for val, err := member.PollTimeout(50 * time.Millisecond); err == nil; val, err = member.PollTimeout(50 * time.Millisecond) {
+				atomic.AddInt32(&received[val.(int)], 1)
+			}
+		}(member)
+	}
+
+	wg.Wait()
+	broker.Close(-1)
+
+	for val, count := range received {
+		if count != 1 {
+			t.Errorf("Invalid Receive Count for %d: Expected: 1 Obtained: %d", val, count)
+		}
+	}
+
+	if name := members[0].Name(); name != "workers" {
+		t.Errorf("Invalid Name: Expected: workers Obtained: %s", name)
+	}
+}
+
+func TestBrokerConsumerGroupUnsubscribe(t *testing.T) {
+	broker := NewBroker()
+	defer broker.Close(-1)
+
+	first := broker.SubscribeGroup("workers", ExactMatcher("jobs"))
+	second := broker.SubscribeGroup("workers", ExactMatcher("jobs"))
+
+	// Unsubscribing the same member twice shouldn't remove the group.
+	first.Unsubscribe(-1)
+	first.Unsubscribe(-1)
+
+	if count := broker.Publish("jobs", "job-1"); count != 1 {
+		t.Errorf("Invalid Publish Count: Expected: 1 Obtained: %d", count)
+	}
+
+	second.Unsubscribe(-1)
+
+	if count := broker.Publish("jobs", "job-2"); count != 0 {
+		t.Errorf("Invalid Publish Count: Expected: 0 Obtained: %d", count)
+	}
+
+	if val, ok := second.Poll(); !ok || val != "job-1" {
+		t.Errorf("Expected Value: job-1, Obtained: %v %v", val, ok)
+	}
+
+	if _, ok := second.Poll(); ok {
+		t.Error("Poll after Unsubscribe should be False")
+	}
+}
+
+func TestBrokerCompositeMatcher(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerCompositeMatcher(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerCompositeMatcher(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerCompositeMatcher(t *testing.T, broker Broker) {
+	defer broker.Close(-1)
+
+	// The patterns overlap on "users.created", yet it should be received once.
+	sub := broker.Subscribe(AnyOf(
+		ExactMatcher("users.created"),
+		MustTopicMatcher("users.#"),
+		Not(PrefixMatcher("users.")),
+	))
+
+	topics := []string{"users.created", "users.deleted", "orders.created", "users.created"}
+	for _, topic := range topics {
+		broker.Publish(topic, topic)
+	}
+
+	for _, expected := range topics {
+		if val, err := sub.PollTimeout(time.Second); err != nil || val != expected {
+			t.Errorf("Invalid Value: Expected: %s Obtained: %v %v", expected, val, err)
+		}
+	}
+
+	if val, ok, _ := sub.TryPoll(); ok {
+		t.Errorf("Message should be received once: Obtained: %v", val)
+	}
+}
+
+func TestBrokerHeaderMatcher(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerHeaderMatcher(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerHeaderMatcher(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerHeaderMatcher(t *testing.T, broker Broker) {
+	defer broker.Close(-1)
+
+	sub := broker.SubscribeMessage(HeaderMatcher{"region": "eu", "x-match": "all"})
+
+	broker.PublishMessage(&Message{Topic: "users", Headers: map[string]string{"region": "us"}, Payload: "record-1"})
+	broker.PublishMessage(&Message{Topic: "orders", Headers: map[string]string{"region": "eu"}, Payload: "record-2"})
+	broker.Publish("users", "record-3")
+
+	if msg, err := sub.PollTimeout(time.Second); err != nil || msg != "record-2" {
+		t.Errorf("Invalid Value: Expected: record-2 Obtained: %v %v", msg, err)
+	}
+
+	if val, ok, _ := sub.TryPoll(); ok {
+		t.Errorf("Message should not be received: Obtained: %v", val)
+	}
+}
+
+func TestBrokerFilter(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerFilter(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerFilter(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerFilter(t *testing.T, broker Broker) {
+	defer broker.Close(-1)
+
+	sub := broker.SubscribeWithOptions(ExactMatcher("orders"), WithFilter(MustFilter("priority > 3 AND region IN ('eu', 'us')")))
+
+	broker.PublishMessage(&Message{Topic: "orders", Headers: map[string]string{"priority": "5", "region": "in"}, Payload: "record-1"})
+	broker.PublishMessage(&Message{Topic: "orders", Headers: map[string]string{"priority": "5", "region": "eu"}, Payload: "record-2"})
+	broker.Publish("orders", map[string]interface{}{"priority": 1, "region": "us"})
+	broker.Publish("orders", map[string]interface{}{"priority": 4, "region": "us"})
+
+	if val, err := sub.PollTimeout(time.Second); err != nil || val != "record-2" {
+		t.Errorf("Invalid Value: Expected: record-2 Obtained: %v %v", val, err)
+	}
+
+	if val, err := sub.PollTimeout(time.Second); err != nil || val.(map[string]interface{})["priority"] != 4 {
+		t.Errorf("Invalid Value: Expected: priority 4 Obtained: %v %v", val, err)
+	}
+
+	if val, ok, _ := sub.TryPoll(); ok {
+		t.Errorf("Filtered out message should not be received: Obtained: %v", val)
+	}
+}
+
+func TestBrokerRegistry(t *testing.T) {
+	registry := NewRegistry()
+	RegisterType[int](registry, ExactMatcher("users.count"))
+
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerRegistry(t, NewBrokerWithOptions(WithRegistry(registry)))
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerRegistry(t, NewAsyncBrokerWithOptions(WithRegistry(registry)))
+	})
+}
+
+func testBrokerRegistry(t *testing.T, broker Broker) {
+	defer broker.Close(-1)
+
+	sub := broker.Subscribe(ExactMatcher("users.count"))
+
+	if count, err := broker.PublishE("users.count", int64(1)); count != 0 || !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Invalid Publish: Expected: 0 %v Obtained: %d %v", ErrTypeMismatch, count, err)
+	}
+
+	if count := broker.Publish("users.count", "1"); count != 0 {
+		t.Errorf("Invalid Publish Count: Expected: 0 Obtained: %d", count)
+	}
+
+	if count, err := broker.PublishMessageE(&Message{Topic: "users.count", Payload: 1}); count != 1 || err != nil {
+		t.Errorf("Invalid Publish: Expected: 1 <nil> Obtained: %d %v", count, err)
+	}
+
+	if val, err := sub.PollTimeout(time.Second); err != nil || val.(int) != 1 {
+		t.Errorf("Invalid Value: Expected: 1 Obtained: %v %v", val, err)
+	}
+
+	if val, ok, _ := sub.TryPoll(); ok {
+		t.Errorf("Rejected payload should not be received: Obtained: %v", val)
+	}
+}
+
+func TestBrokerPublishAfterClose(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerPublishAfterClose(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerPublishAfterClose(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerPublishAfterClose(t *testing.T, broker Broker) {
+	sub := broker.Subscribe(ExactMatcher("all"))
+
+	// Publishers racing with Close should neither panic nor lose the published data.
+	wg := sync.WaitGroup{}
+	published := int32(0)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, err := broker.PublishE("all", j); err == nil {
+					atomic.AddInt32(&published, 1)
+				} else if err != ErrBrokerClosed {
+					t.Errorf("Invalid Publish Error: Expected: %v Obtained: %v", ErrBrokerClosed, err)
+				}
+			}
+		}()
+	}
+
+	time.Sleep(time.Millisecond)
+	broker.Close(-1)
+	wg.Wait()
+
+	received := int32(0)
+	for _, ok := sub.Poll(); ok; _, ok = sub.Poll() {
+		received++
+	}
+
+	if received != atomic.LoadInt32(&published) {
+		t.Errorf("Invalid Received Count: Expected: %d Obtained: %d", published, received)
+	}
+
+	if count, err := broker.PublishE("all", "record"); count != 0 || err != ErrBrokerClosed {
+		t.Errorf("Invalid Publish: Expected: 0 %v Obtained: %d %v", ErrBrokerClosed, count, err)
+	}
+
+	if count := broker.Publish("all", "record"); count != 0 {
+		t.Errorf("Invalid Publish Count: Expected: 0 Obtained: %d", count)
+	}
+}
+
+func TestBrokerSubscribeAfterClose(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerSubscribeAfterClose(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerSubscribeAfterClose(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerSubscribeAfterClose(t *testing.T, broker Broker) {
+	broker.Close(0)
+
+	sub := broker.SubscribeWithOptions(ExactMatcher("all"), WithName("users"), WithDurable())
+	if sub.Name() != "users" {
+		t.Errorf("Invalid Name: Expected: users Obtained: %s", sub.Name())
+	}
+
+	if val, ok := sub.Poll(); ok {
+		t.Errorf("Poll should return false once the broker is closed, Obtained: %v", val)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if _, err := sub.PollContext(ctx); err != queue.ErrClosed {
+		t.Errorf("Invalid PollContext Error: Expected: %v Obtained: %v", queue.ErrClosed, err)
+	}
+
+	sub.Unsubscribe(0)
+}
+
+func TestBrokerPublishErrors(t *testing.T) {
+	broker := NewBroker()
+	defer broker.Close(-1)
+
+	broker.SubscribeWithOptions(ExactMatcher("all"), WithCapacity(1), WithOverflowPolicy(queue.Reject))
+	broker.Subscribe(ExactMatcher("all"))
+
+	if count, err := broker.PublishE("all", 1); count != 2 || err != nil {
+		t.Errorf("Invalid Publish: Expected: 2 <nil> Obtained: %d %v", count, err)
+	}
+
+	if count, err := broker.PublishE("all", 2); count != 1 || err != ErrQueueFull {
+		t.Errorf("Invalid Publish: Expected: 1 %v Obtained: %d %v", ErrQueueFull, count, err)
+	}
+
+	if count, err := broker.PublishMessageE(nil); count != 0 || err != ErrInvalidMessage {
+		t.Errorf("Invalid Publish: Expected: 0 %v Obtained: %d %v", ErrInvalidMessage, count, err)
+	}
+}
+
+func TestAsyncBrokerCloseDeliversPending(t *testing.T) {
+	for _, timeOut := range []time.Duration{-1, time.Second} {
+		broker := NewAsyncBroker()
+		sub := broker.Subscribe(ExactMatcher("all"))
+
+		received := make(chan int)
+		go func() {
+			count := 0
+			for _, ok := sub.Poll(); ok; _, ok = sub.Poll() {
+				count++
+			}
+			received <- count
+		}()
+
+		for i := 0; i < 1000; i++ {
+			broker.Publish("all", i)
+		}
+
+		// The data pending in the broker should be delivered, before closing the subscribers.
+		broker.Close(timeOut)
+
+		if count := <-received; count != 1000 {
+			t.Errorf("Invalid Received Count for timeOut %v: Expected: 1000 Obtained: %d", timeOut, count)
+		}
+	}
+}
+
+func TestBrokerPublishContext(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerPublishContext(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerPublishContext(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerPublishContext(t *testing.T, broker Broker) {
+	defer broker.Close(0)
+
+	slow := broker.SubscribeWithOptions(ExactMatcher("all"), WithName("slow"), WithCapacity(1))
+	fast := broker.SubscribeWithOptions(ExactMatcher("all"), WithName("fast"))
+
+	if count, err := broker.PublishContext(context.Background(), "all", 1); count != 2 || err != nil {
+		t.Errorf("Invalid Publish: Expected: 2 <nil> Obtained: %d %v", count, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// The slow subscription is full, hence it couldn't be served within the timeout.
+	count, err := broker.PublishContext(ctx, "all", 2)
+	var publishErr *PublishError
+	if count != 1 || !errors.As(err, &publishErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Invalid Publish: Expected: 1 %v Obtained: %d %v", context.DeadlineExceeded, count, err)
+	}
+
+	if len(publishErr.Subscriptions) != 1 || publishErr.Subscriptions[0] != "slow" {
+		t.Errorf("Invalid Unserved Subscriptions: Expected: [slow] Obtained: %v", publishErr.Subscriptions)
+	}
+
+	// The publisher waits until the slow subscription is polled.
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		slow.Poll()
+	}()
+
+	if count, err := broker.PublishContext(context.Background(), "all", 3); count != 2 || err != nil {
+		t.Errorf("Invalid Publish: Expected: 2 <nil> Obtained: %d %v", count, err)
+	}
+
+	for _, expected := range []int{1, 2, 3} {
+		if val, err := fast.PollTimeout(time.Second); err != nil || val != expected {
+			t.Errorf("Invalid Value: Expected: %d Obtained: %v %v", expected, val, err)
+		}
+	}
+}
+
+func TestBrokerMessageExpiration(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerMessageExpiration(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerMessageExpiration(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerMessageExpiration(t *testing.T, broker Broker) {
+	defer broker.Close(-1)
+
+	sub := broker.SubscribeWithOptions(ExactMatcher("ticks"), WithTTL(50*time.Millisecond), WithDeadLetter("expired"))
+	expired := broker.Subscribe(ExactMatcher("expired"))
+
+	broker.PublishMessage(&Message{Topic: "ticks", Payload: "tick-1", TTL: 10 * time.Millisecond})
+	broker.Publish("ticks", "tick-2")
+	broker.PublishMessage(&Message{Topic: "ticks", Payload: "tick-3", TTL: time.Hour})
+
+	time.Sleep(20 * time.Millisecond)
+	broker.Publish("ticks", "tick-4")
+
+	// tick-1 expired by its TTL, whereas others are within the TTL of subscription.
+	for _, expected := range []string{"tick-2", "tick-3", "tick-4"} {
+		if val, err := sub.PollTimeout(time.Second); err != nil || val != expected {
+			t.Errorf("Invalid Value: Expected: %s Obtained: %v %v", expected, val, err)
+		}
+	}
+
+	broker.Publish("ticks", "tick-5")
+	broker.PublishMessage(&Message{Topic: "ticks", Payload: "tick-6", TTL: time.Hour})
+	broker.Publish("ticks", "tick-7")
+	time.Sleep(60 * time.Millisecond)
+	broker.Publish("ticks", "tick-8")
+
+	// The TTL of subscription applies, even if the TTL of message is longer.
+	if values, ok := sub.PollBatch(4, time.Second); !ok || len(values) != 1 || values[0] != "tick-8" {
+		t.Errorf("Invalid Values: Expected: [tick-8] Obtained: %v %v", values, ok)
+	}
+
+	if stats := sub.Stats(); stats.Expired != 4 {
+		t.Errorf("Invalid Expired Count: Expected: 4 Obtained: %d", stats.Expired)
+	}
+
+	msg, err := expired.PollMessageContext(context.Background())
+	if err != nil || msg.Payload != "tick-1" || msg.Headers[HeaderDeathReason] != ReasonExpired {
+		t.Errorf("Invalid Dead Letter: Expected: tick-1 %s Obtained: %+v %v", ReasonExpired, msg, err)
+	}
+}
+
+func TestBrokerScheduledPublish(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerScheduledPublish(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerScheduledPublish(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerScheduledPublish(t *testing.T, broker Broker) {
+	sub := broker.Subscribe(ExactMatcher("jobs"))
+
+	now := time.Now()
+	broker.PublishAt("jobs", "job-3", now.Add(60*time.Millisecond))
+	broker.PublishAfter("jobs", "job-2", 30*time.Millisecond)
+	broker.PublishMessageAt(&Message{Topic: "jobs", Payload: "job-1"}, now.Add(-time.Second))
+	broker.PublishAt("jobs", "job-4", now.Add(60*time.Millisecond))
+
+	for _, expected := range []string{"job-1", "job-2", "job-3", "job-4"} {
+		if val, err := sub.PollTimeout(time.Second); err != nil || val != expected {
+			t.Errorf("Invalid Value: Expected: %s Obtained: %v %v", expected, val, err)
+		}
+	}
+
+	if elapsed := time.Since(now); elapsed < 60*time.Millisecond {
+		t.Errorf("Scheduled data published early: Expected: >= 60ms Obtained: %v", elapsed)
+	}
+
+	broker.PublishAfter("jobs", "job-5", time.Hour)
+	broker.Close(-1)
+
+	if err := broker.PublishAfter("jobs", "job-6", 0); err != ErrBrokerClosed {
+		t.Errorf("Invalid Publish Error: Expected: %v Obtained: %v", ErrBrokerClosed, err)
+	}
+
+	if val, ok := sub.Poll(); ok {
+		t.Errorf("Data yet to be due should be discarded on closing: Obtained: %v", val)
+	}
+}
+
+func TestBrokerScheduledPublishFull(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerScheduledPublishFull(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerScheduledPublishFull(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerScheduledPublishFull(t *testing.T, broker Broker) {
+	defer broker.Close(0)
+
+	full := broker.SubscribeWithOptions(ExactMatcher("a"), WithCapacity(1), WithOverflowPolicy(queue.Block))
+	sub := broker.Subscribe(ExactMatcher("b"))
+
+	broker.Publish("a", "record-1")
+	broker.PublishAfter("a", "record-2", time.Millisecond)
+	broker.PublishAfter("b", "record-3", 5*time.Millisecond)
+
+	// The data due later shouldn't wait for the full subscription.
+	if val, err := sub.PollTimeout(500 * time.Millisecond); err != nil || val != "record-3" {
+		t.Errorf("Invalid Value: Expected: record-3 Obtained: %v %v", val, err)
+	}
+
+	if val, err := full.PollTimeout(time.Second); err != nil || val != "record-1" {
+		t.Errorf("Invalid Value: Expected: record-1 Obtained: %v %v", val, err)
+	}
+}
+
+func TestBrokerScheduledPublishRoutines(t *testing.T) {
+	broker := NewBroker()
+	sub := broker.Subscribe(ExactMatcher("jobs"))
+
+	routines := runtime.NumGoroutine()
+
+	// The scheduled data are served by a single routine, rather than a timer per data.
+	at := time.Now().Add(10 * time.Millisecond)
+	for i := 0; i < 1000; i++ {
+		broker.PublishAt("jobs", i, at.Add(time.Duration(1000-i)*time.Microsecond))
+	}
+
+	if count := runtime.NumGoroutine(); count > routines+1 {
+		t.Errorf("Invalid Routine Count: Expected: <= %d Obtained: %d", routines+1, count)
+	}
+
+	for i := 999; i >= 0; i-- {
+		if val, err := sub.PollTimeout(time.Second); err != nil || val != i {
+			t.Fatalf("Invalid Value: Expected: %d Obtained: %v %v", i, val, err)
+		}
+	}
+
+	broker.Close(-1)
+}
+
+func TestBrokerPriority(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerPriority(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerPriority(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerPriority(t *testing.T, broker Broker) {
+	defer broker.Close(-1)
+
+	sub := broker.SubscribeWithOptions(ExactMatcher("alerts"), WithPriority())
+	fifo := broker.Subscribe(ExactMatcher("alerts"))
+
+	// PublishMessageContext returns once the message is pushed to the subscriptions,
+	// and a push to a priority subscription returns once the message is placed by its priority.
+	// Hence, the backlog is ordered before polling.
+	for _, msg := range []*Message{
+		{Topic: "alerts", Payload: "info-1"},
+		{Topic: "alerts", Payload: "info-2"},
+		{Topic: "alerts", Payload: "critical-1", Priority: 2},
+		{Topic: "alerts", Payload: "warning-1", Priority: 1},
+		{Topic: "alerts", Payload: "critical-2", Priority: 2},
+	} {
+		broker.PublishMessageContext(context.Background(), msg)
+	}
+
+	for _, expected := range []string{"critical-1", "critical-2", "warning-1", "info-1", "info-2"} {
+		if val, err := sub.PollTimeout(time.Second); err != nil || val != expected {
+			t.Errorf("Invalid Value: Expected: %s Obtained: %v %v", expected, val, err)
+		}
+	}
+
+	// The subscriptions not opting into priority poll in the order of publishing.
+	for _, expected := range []string{"info-1", "info-2", "critical-1", "warning-1", "critical-2"} {
+		if val, err := fifo.PollTimeout(time.Second); err != nil || val != expected {
+			t.Errorf("Invalid Value: Expected: %s Obtained: %v %v", expected, val, err)
+		}
+	}
+}
+
+func TestBrokerRetainedMessages(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerRetainedMessages(t, NewBroker())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerRetainedMessages(t, NewAsyncBroker())
+	})
+}
+
+func testBrokerRetainedMessages(t *testing.T, broker Broker) {
+	defer broker.Close(-1)
+
+	// PublishMessageContext returns once the message is published, hence it is retained before subscribing.
+	for _, msg := range []*Message{
+		{Topic: "config/db", Payload: "db-1", Retain: true},
+		{Topic: "config/cache", Payload: "cache-1", Retain: true},
+		{Topic: "config/db", Payload: "db-2", Retain: true},
+		{Topic: "config/db", Payload: "db-3"},
+		{Topic: "config/log", Payload: "log-1", Retain: true},
+		{Topic: "config/log", Retain: true},
+		{Topic: "config/tmp", Payload: "tmp-1", Retain: true, TTL: time.Nanosecond},
+	} {
+		broker.PublishMessageContext(context.Background(), msg)
+	}
+
+	// The last retained message of every matching topic is delivered, the cleared & expired ones are not.
+	sub := broker.Subscribe(PrefixMatcher("config/"))
+	if values, ok := sub.PollBatch(10, 10*time.Millisecond); !ok || fmt.Sprint(values) != "[cache-1 db-2]" {
+		t.Errorf("Invalid Values: Expected: [cache-1 db-2] Obtained: %v %v", values, ok)
+	}
+
+	broker.PublishMessageContext(context.Background(), &Message{Topic: "config/db", Payload: "db-4", Retain: true,
+		Headers: map[string]string{"env": "test"}})
+	if val, err := sub.PollTimeout(time.Second); err != nil || val != "db-4" {
+		t.Errorf("Invalid Value: Expected: db-4 Obtained: %v %v", val, err)
+	}
+
+	db := broker.SubscribeWithOptions(ExactMatcher("config/db"), WithFilter(MustFilter("env = 'prod'")))
+	if val, ok, _ := db.TryPoll(); ok {
+		t.Errorf("Retained message filtered out should not be delivered: Obtained: %v", val)
+	}
+}
+
+func TestBrokerHistory(t *testing.T) {
+	opts := []BrokerOption{
+		WithHistory(ExactMatcher("events/audit"), 0, time.Hour),
+		WithHistory(PrefixMatcher("events/"), 3, 0),
+	}
+
+	t.Run("SyncBroker", func(t *testing.T) {
+		testBrokerHistory(t, NewBrokerWithOptions(opts...))
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testBrokerHistory(t, NewAsyncBrokerWithOptions(opts...))
+	})
+}
+
+func testBrokerHistory(t *testing.T, broker Broker) {
+	defer broker.Close(-1)
+
+	// PublishMessageContext returns once the message is published, hence it is in the history before subscribing.
+	for i := 1; i <= 5; i++ {
+		broker.PublishMessageContext(context.Background(), &Message{Topic: "events/login", Payload: fmt.Sprint("login-", i)})
+		broker.PublishMessageContext(context.Background(), &Message{Topic: "events/audit", Payload: fmt.Sprint("audit-", i)})
+		broker.PublishMessageContext(context.Background(), &Message{Topic: "metrics", Payload: fmt.Sprint("metric-", i)})
+	}
+	broker.PublishMessageContext(context.Background(), &Message{Topic: "events/login", Payload: "login-6", Retain: true})
+
+	// The replayed messages are pushed on subscribing, hence they are available without waiting.
+	poll := func(sub Subscription) string {
+		values := []interface{}{}
+		for val, ok, _ := sub.TryPoll(); ok; val, ok, _ = sub.TryPoll() {
+			values = append(values, val)
+		}
+		return fmt.Sprint(values)
+	}
+
+	tests := []struct {
+		name     string
+		matcher  Matcher
+		start    StartPosition
+		expected string
+	}{
+		// The retained message is delivered once, even though it is replayed.
+		{"now", PrefixMatcher("events/"), FromNow(), "[login-6]"},
+		{"earliest", ExactMatcher("events/login"), FromEarliest(), "[login-4 login-5 login-6]"},
+		{"earliest-unlimited", ExactMatcher("events/audit"), FromEarliest(), "[audit-1 audit-2 audit-3 audit-4 audit-5]"},
+		{"offset", PrefixMatcher("events/"), FromOffset(4), "[login-5 audit-5 login-6]"},
+		{"offset-evicted", ExactMatcher("events/login"), FromOffset(1), "[login-4 login-5 login-6]"},
+		{"time", PrefixMatcher("events/"), FromTime(time.Now().Add(time.Minute)), "[login-6]"},
+		{"no-history", ExactMatcher("metrics"), FromEarliest(), "[]"},
+	}
+
+	for _, test := range tests {
+		sub := broker.SubscribeWithOptions(test.matcher, WithStartFrom(test.start))
+		if obtained := poll(sub); obtained != test.expected {
+			t.Errorf("Invalid Replay from %s: Expected: %s Obtained: %s", test.name, test.expected, obtained)
+		}
+	}
+
+	// The replayed messages are followed by the messages published later.
+	sub := broker.SubscribeWithOptions(ExactMatcher("events/login"), WithStartFrom(FromOffset(5)))
+	broker.PublishMessageContext(context.Background(), &Message{Topic: "events/login", Payload: "login-7"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	msgs := []*Message{}
+	for i := 0; i < 2; i++ {
+		if msg, err := sub.PollMessageContext(ctx); err == nil {
+			msgs = append(msgs, msg)
+		}
+	}
+
+	if len(msgs) != 2 || msgs[0].Payload != "login-6" || msgs[1].Payload != "login-7" || msgs[1].Offset != 6 {
+		t.Errorf("Invalid Messages: Expected: login-6 login-7 at offset 6 Obtained: %+v", msgs)
+	}
+}
@@ -0,0 +1,195 @@
+package gomq
+
+import "time"
+
+// historyConfig keeps the history of the topics matched by matcher.
+type historyConfig struct {
+	matcher   Matcher
+	limit     int
+	retention time.Duration
+}
+
+// StartPosition is the position in the history of its topics, from which a subscription starts receiving the messages.
+// The history is kept only for the topics configured by WithHistory.
+type StartPosition struct {
+	replay bool
+	offset uint64
+	at     time.Time
+}
+
+// FromNow starts the subscription from the messages published after subscribing, which is the default.
+func FromNow() StartPosition {
+	return StartPosition{}
+}
+
+// FromEarliest starts the subscription from the earliest message in the history of its topics.
+func FromEarliest() StartPosition {
+	return StartPosition{replay: true}
+}
+
+// FromOffset starts the subscription from the message at offset in the history of its topics.
+// If the message at offset is no longer in the history, it starts from the earliest one.
+func FromOffset(offset uint64) StartPosition {
+	return StartPosition{replay: true, offset: offset}
+}
+
+// FromTime starts the subscription from the earliest message in the history of its topics, published at or after t.
+func FromTime(t time.Time) StartPosition {
+	return StartPosition{replay: true, at: t}
+}
+
+// includes returns true, if the message is at or after the position.
+func (p StartPosition) includes(msg *Message) bool {
+	return p.replay && msg.Offset >= p.offset && !msg.Timestamp.Before(p.at)
+}
+
+// topicHistory is a ring buffer of the last messages published to a topic.
+// It is guarded by the broker's lock.
+type topicHistory struct {
+	ring []*Message
+	head int
+	size int
+
+	// next is the offset of the next message published to the topic.
+	next uint64
+
+	limit     int
+	retention time.Duration
+}
+
+func newTopicHistory(cfg *historyConfig) *topicHistory {
+	return &topicHistory{limit: cfg.limit, retention: cfg.retention}
+}
+
+// append adds the message to the history, evicting the messages beyond its limit or retention.
+// The message is assigned the next offset of the topic.
+func (h *topicHistory) append(msg *Message, now time.Time) {
+	msg.Offset = h.next
+	h.next++
+
+	if h.limit > 0 && h.size == h.limit {
+		h.pop()
+	}
+
+	if h.size == len(h.ring) {
+		h.grow()
+	}
+
+	h.ring[(h.head+h.size)%len(h.ring)] = msg
+	h.size++
+
+	h.trim(now)
+}
+
+func (h *topicHistory) at(i int) *Message {
+	return h.ring[(h.head+i)%len(h.ring)]
+}
+
+// pop evicts the earliest message.
+func (h *topicHistory) pop() {
+	h.ring[h.head] = nil
+	h.head = (h.head + 1) % len(h.ring)
+	h.size--
+}
+
+// grow doubles the capacity of the ring, up to the limit.
+func (h *topicHistory) grow() {
+	capacity := 2 * len(h.ring)
+	if capacity == 0 {
+		capacity = 16
+	}
+
+	if h.limit > 0 && capacity > h.limit {
+		capacity = h.limit
+	}
+
+	ring := make([]*Message, capacity)
+	for i := 0; i < h.size; i++ {
+		ring[i] = h.at(i)
+	}
+
+	h.ring = ring
+	h.head = 0
+}
+
+// trim evicts the messages older than the retention.
+func (h *topicHistory) trim(now time.Time) {
+	if h.retention <= 0 {
+		return
+	}
+
+	for h.size > 0 && now.Sub(h.at(0).Timestamp) > h.retention {
+		h.pop()
+	}
+}
+
+// historyConfig returns the history configured for the topic, else nil.
+// The configurations are not modified once the broker is created, hence they are read without holding the lock.
+func (b *brokerBase) historyConfig(topic string) *historyConfig {
+	for i := range b.histories {
+		if b.histories[i].matcher.MatchString(topic) {
+			return &b.histories[i]
+		}
+	}
+
+	return nil
+}
+
+// unsafeRecord adds the message to the history of its topic.
+// It is called while holding the lock.
+func (b *brokerBase) unsafeRecord(msg *Message, cfg *historyConfig, now time.Time) {
+	h, ok := b.logs[msg.Topic]
+	if !ok {
+		if b.logs == nil {
+			b.logs = map[string]*topicHistory{}
+		}
+
+		// Pruning once the count of logs doubles since the last time, amortizes it over the new topics.
+		if len(b.logs) >= b.pruneAt {
+			b.unsafePrune(now)
+			b.pruneAt = 2 * len(b.logs)
+		}
+
+		h = newTopicHistory(cfg)
+		b.logs[msg.Topic] = h
+	}
+
+	// The message might be older than the retention itself.
+	if h.append(msg, now); h.size == 0 {
+		delete(b.logs, msg.Topic)
+	}
+}
+
+// unsafePrune removes the histories, whose messages are all evicted by their retention.
+// Hence, the topics no longer published to are not held forever.
+// It is called while holding the lock.
+func (b *brokerBase) unsafePrune(now time.Time) {
+	for topic, h := range b.logs {
+		if h.trim(now); h.size == 0 {
+			delete(b.logs, topic)
+		}
+	}
+}
+
+// unsafeReplay returns the messages in the history of the topics matching the new subscription, from the position.
+// It is called while holding the lock.
+func (b *brokerBase) unsafeReplay(qm *queueMatcher, pos StartPosition) []*Message {
+	if !pos.replay || len(b.logs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	b.unsafePrune(now)
+
+	replayed := []*Message{}
+	for _, h := range b.logs {
+		for i := 0; i < h.size; i++ {
+			msg := h.at(i)
+			if pos.includes(msg) && !msg.expired(0, now) && qm.matches(msg) {
+				replayed = append(replayed, msg)
+			}
+		}
+	}
+
+	return replayed
+}
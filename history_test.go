@@ -0,0 +1,103 @@
+package gomq
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// payloads returns the payloads in the history, from the earliest.
+func (h *topicHistory) payloads() []interface{} {
+	payloads := []interface{}{}
+	for i := 0; i < h.size; i++ {
+		payloads = append(payloads, h.at(i).Payload)
+	}
+
+	return payloads
+}
+
+func TestTopicHistoryLimit(t *testing.T) {
+	h := newTopicHistory(&historyConfig{limit: 20})
+
+	now := time.Now()
+	for i := 0; i < 50; i++ {
+		msg := &Message{Payload: i, Timestamp: now}
+		h.append(msg, now)
+
+		if msg.Offset != uint64(i) {
+			t.Errorf("Invalid Offset: Expected: %d Obtained: %d", i, msg.Offset)
+		}
+	}
+
+	// The ring doesn't grow beyond the limit.
+	if len(h.ring) != 20 {
+		t.Errorf("Invalid Ring Size: Expected: 20 Obtained: %d", len(h.ring))
+	}
+
+	payloads := h.payloads()
+	if len(payloads) != 20 || payloads[0] != 30 || payloads[19] != 49 {
+		t.Errorf("Invalid History: Expected: [30 ... 49] Obtained: %v", payloads)
+	}
+}
+
+func TestTopicHistoryRetention(t *testing.T) {
+	h := newTopicHistory(&historyConfig{retention: time.Minute})
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		h.append(&Message{Payload: i, Timestamp: now.Add(time.Duration(i) * time.Minute)}, now)
+	}
+
+	h.trim(now.Add(3*time.Minute + time.Second))
+	if payloads := h.payloads(); fmt.Sprint(payloads) != "[3 4]" {
+		t.Errorf("Invalid History: Expected: [3 4] Obtained: %v", payloads)
+	}
+
+	h.append(&Message{Payload: 5, Timestamp: now.Add(10 * time.Minute)}, now.Add(10*time.Minute))
+	if payloads := h.payloads(); fmt.Sprint(payloads) != "[5]" {
+		t.Errorf("Invalid History: Expected: [5] Obtained: %v", payloads)
+	}
+}
+
+func TestBrokerHistoryPrune(t *testing.T) {
+	b := &brokerBase{}
+	cfg := &historyConfig{retention: time.Minute}
+
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		b.unsafeRecord(&Message{Topic: fmt.Sprintf("events/old-%d", i), Timestamp: now}, cfg, now)
+	}
+
+	// The histories of the old topics are emptied by the retention, hence they are pruned on recording the new ones.
+	later := now.Add(2 * time.Minute)
+	for i := 0; i < 100; i++ {
+		b.unsafeRecord(&Message{Topic: fmt.Sprintf("events/new-%d", i), Timestamp: later}, cfg, later)
+	}
+
+	if len(b.logs) != 100 {
+		t.Errorf("Invalid History Count: Expected: 100 Obtained: %d", len(b.logs))
+	}
+
+	for topic := range b.logs {
+		if !strings.HasPrefix(topic, "events/new-") {
+			t.Errorf("History of %s should be pruned", topic)
+		}
+	}
+
+	// A message older than the retention isn't kept.
+	b.unsafeRecord(&Message{Topic: "events/stale", Timestamp: now}, cfg, later)
+	if _, ok := b.logs["events/stale"]; ok {
+		t.Errorf("History of events/stale should be pruned")
+	}
+}
+
+func TestWithHistoryUnlimited(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("History without any limit should panic")
+		}
+	}()
+
+	WithHistory(ExactMatcher("events"), 0, 0)
+}
@@ -0,0 +1,284 @@
+package gomq
+
+// subscriptionIndex indexes the subscribers by their matchers,
+// hence publishing visits only the subscribers matching the topic.
+//
+// ExactMatcher, TopicMatcher & MQTTMatcher are indexed by their topics, as are the matchers combined by AnyOf.
+// Any other MessageMatcher is matched against every message.
+//
+// It is immutable once built, hence it can be read without holding the broker's lock.
+// Subscribing & unsubscribing derive a new index, copying only the parts of it they modify.
+type subscriptionIndex struct {
+	// exact is sharded by the hash of topics, hence a change copies only the shard of its topic.
+	exact  [exactShards]map[string][]*queueMatcher
+	topics *topicNode
+	mqtt   *topicNode
+	others []*queueMatcher
+}
+
+// topicNode is the node of a trie, built on the levels of topic patterns.
+// A node with no subscribers & children is removed, hence a nil node is an empty trie.
+type topicNode struct {
+	children map[string]*topicNode
+
+	// single is the child for the wildcard matching exactly one level.
+	single *topicNode
+
+	// multi is the child for the wildcard matching multiple levels.
+	multi *topicNode
+
+	subscribers []*queueMatcher
+}
+
+// exactShards is the count of shards of the exact topics.
+const exactShards = 64
+
+// exactShard returns the shard of the topic, by its FNV-1a hash.
+func exactShard(topic string) int {
+	hash := uint32(2166136261)
+	for i := 0; i < len(topic); i++ {
+		hash ^= uint32(topic[i])
+		hash *= 16777619
+	}
+
+	return int(hash % exactShards)
+}
+
+func newSubscriptionIndex() *subscriptionIndex {
+	return &subscriptionIndex{}
+}
+
+// with returns a copy of the index, including the subscriber.
+func (idx *subscriptionIndex) with(qm *queueMatcher) *subscriptionIndex {
+	c := *idx
+	c.update(qm, true)
+	return &c
+}
+
+// without returns a copy of the index, excluding the subscriber.
+func (idx *subscriptionIndex) without(qm *queueMatcher) *subscriptionIndex {
+	c := *idx
+	c.update(qm, false)
+	return &c
+}
+
+// update includes or excludes the subscriber in the copy of an index.
+// The parts shared with the original index are copied before being modified.
+func (idx *subscriptionIndex) update(qm *queueMatcher, include bool) {
+	if tm, ok := qm.matcher.(topicMatcher); ok && idx.indexable(tm.Matcher) {
+		idx.updateTopic(tm.Matcher, qm, include)
+		return
+	}
+
+	// The whole matcher of the subscriber is checked, hence it is listed once.
+	idx.others = updated(idx.others, qm, include)
+}
+
+// indexable returns true, if the matcher is indexed by its topics.
+func (idx *subscriptionIndex) indexable(matcher Matcher) bool {
+	switch m := matcher.(type) {
+	case ExactMatcher, *TopicMatcher, *MQTTMatcher:
+		return true
+	case anyOf:
+		for _, child := range m {
+			if !idx.indexable(child) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+func (idx *subscriptionIndex) updateTopic(matcher Matcher, qm *queueMatcher, include bool) {
+	switch m := matcher.(type) {
+	case ExactMatcher:
+		shard := exactShard(string(m))
+		exact := make(map[string][]*queueMatcher, len(idx.exact[shard])+1)
+		for topic, subscribers := range idx.exact[shard] {
+			exact[topic] = subscribers
+		}
+
+		if subscribers := updated(exact[string(m)], qm, include); len(subscribers) > 0 {
+			exact[string(m)] = subscribers
+		} else {
+			delete(exact, string(m))
+		}
+		idx.exact[shard] = exact
+	case *TopicMatcher:
+		idx.topics = idx.topics.update(m.words, "*", "#", qm, include)
+	case *MQTTMatcher:
+		idx.mqtt = idx.mqtt.update(m.levels, "+", "#", qm, include)
+	case anyOf:
+		// The subscriber matches if any of the matchers match,
+		// the duplicates are removed while matching.
+		for _, child := range m {
+			idx.updateTopic(child, qm, include)
+		}
+	}
+}
+
+// updated returns a copy of subscribers, including or excluding qm.
+func updated(subscribers []*queueMatcher, qm *queueMatcher, include bool) []*queueMatcher {
+	if include {
+		// The full slice expression makes append copy, rather than modifying the shared array.
+		return append(subscribers[:len(subscribers):len(subscribers)], qm)
+	}
+
+	remaining := make([]*queueMatcher, 0, len(subscribers))
+	for _, s := range subscribers {
+		if s != qm {
+			remaining = append(remaining, s)
+		}
+	}
+
+	return remaining
+}
+
+// match appends all the subscribers matching the message to matched.
+// The subscribers are in the order of subscription.
+func (idx *subscriptionIndex) match(msg *Message, matched []*queueMatcher) []*queueMatcher {
+	topic := msg.Topic
+
+	matched = append(matched, idx.exact[exactShard(topic)][topic]...)
+	if idx.topics != nil {
+		matched = idx.topics.matchTopic(topic, 0, matched)
+	}
+	if idx.mqtt != nil {
+		matched = idx.mqtt.matchMQTT(topic, matched)
+	}
+
+	for _, qm := range idx.others {
+		if qm.matcher.MatchMessage(msg) {
+			matched = append(matched, qm)
+		}
+	}
+
+	return sortUnique(matched)
+}
+
+// update returns a copy of the trie, including or excluding the subscriber at the levels.
+// Only the nodes on the path of the levels are copied, the rest are shared.
+func (n *topicNode) update(levels []string, single, multi string, qm *queueMatcher, include bool) *topicNode {
+	if n == nil && !include {
+		return nil
+	}
+
+	c := &topicNode{}
+	if n != nil {
+		*c = *n
+	}
+
+	if len(levels) == 0 {
+		c.subscribers = updated(c.subscribers, qm, include)
+	} else {
+		switch level := levels[0]; level {
+		case single:
+			c.single = c.single.update(levels[1:], single, multi, qm, include)
+		case multi:
+			c.multi = c.multi.update(levels[1:], single, multi, qm, include)
+		default:
+			children := make(map[string]*topicNode, len(c.children)+1)
+			for l, child := range c.children {
+				children[l] = child
+			}
+
+			if child := children[level].update(levels[1:], single, multi, qm, include); child != nil {
+				children[level] = child
+			} else {
+				delete(children, level)
+			}
+			c.children = children
+		}
+	}
+
+	if len(c.subscribers) == 0 && len(c.children) == 0 && c.single == nil && c.multi == nil {
+		return nil
+	}
+
+	return c
+}
+
+// matchTopic matches the dot separated words of topic starting at offset, as per TopicMatcher.
+// An offset beyond the length of topic indicates that no words are left.
+func (n *topicNode) matchTopic(topic string, offset int, matched []*queueMatcher) []*queueMatcher {
+	if n.multi != nil {
+		// Matches rest of the words, after skipping zero or more words.
+		for off := offset; ; off = nextLevel(topic, off, '.') {
+			matched = n.multi.matchTopic(topic, off, matched)
+			if off > len(topic) {
+				break
+			}
+		}
+	}
+
+	if offset > len(topic) {
+		return append(matched, n.subscribers...)
+	}
+
+	next := nextLevel(topic, offset, '.')
+	if child, ok := n.children[topic[offset:next-1]]; ok {
+		matched = child.matchTopic(topic, next, matched)
+	}
+
+	if n.single != nil {
+		matched = n.single.matchTopic(topic, next, matched)
+	}
+
+	return matched
+}
+
+// matchMQTT matches the slash separated levels of topic, as per MQTTMatcher.
+func (n *topicNode) matchMQTT(topic string, matched []*queueMatcher) []*queueMatcher {
+	if len(topic) > 0 && topic[0] == '$' {
+		// Topics starting with `$` are not matched by filters starting with a wildcard.
+		root := topicNode{children: n.children}
+		return root.matchLevels(topic, 0, matched)
+	}
+
+	return n.matchLevels(topic, 0, matched)
+}
+
+// matchLevels matches the slash separated levels of topic starting at offset.
+// An offset beyond the length of topic indicates that no levels are left.
+func (n *topicNode) matchLevels(topic string, offset int, matched []*queueMatcher) []*queueMatcher {
+	if n.multi != nil {
+		// Matches the parent level and any number of child levels.
+		matched = append(matched, n.multi.subscribers...)
+	}
+
+	if offset > len(topic) {
+		return append(matched, n.subscribers...)
+	}
+
+	next := nextLevel(topic, offset, '/')
+	if child, ok := n.children[topic[offset:next-1]]; ok {
+		matched = child.matchLevels(topic, next, matched)
+	}
+
+	if n.single != nil {
+		matched = n.single.matchLevels(topic, next, matched)
+	}
+
+	return matched
+}
+
+// sortUnique sorts the subscribers in place by their order of subscription, and removes the duplicates.
+// Insertion sort is used, as only a few subscribers are expected to match a topic.
+func sortUnique(subscribers []*queueMatcher) []*queueMatcher {
+	for i := 1; i < len(subscribers); i++ {
+		for j := i; j > 0 && subscribers[j].id < subscribers[j-1].id; j-- {
+			subscribers[j], subscribers[j-1] = subscribers[j-1], subscribers[j]
+		}
+	}
+
+	unique := subscribers[:0]
+	for _, s := range subscribers {
+		if len(unique) == 0 || s != unique[len(unique)-1] {
+			unique = append(unique, s)
+		}
+	}
+
+	return unique
+}
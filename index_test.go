@@ -0,0 +1,131 @@
+package gomq
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestSubscriptionIndex(t *testing.T) {
+	matchers := []Matcher{
+		ExactMatcher("users.created"),
+		MustTopicMatcher("users.*"),
+		MustTopicMatcher("users.#"),
+		MustTopicMatcher("#.created"),
+		MustTopicMatcher("users.#.#.created"),
+		MustTopicMatcher("#"),
+		MustMQTTMatcher("users/+"),
+		MustMQTTMatcher("users/#"),
+		MustMQTTMatcher("+/+/created"),
+		MustMQTTMatcher("#"),
+		MustMQTTMatcher("$SYS/#"),
+		regexp.MustCompile(`created$`),
+		ExactMatcher("users.created"),
+		MustTopicMatcher("*.*"),
+		AnyOf(ExactMatcher("created"), MustTopicMatcher("users.#"), MustMQTTMatcher("users/#")),
+		AnyOf(PrefixMatcher("orders"), SuffixMatcher("created"), ExactMatcher("users")),
+		AllOf(MustTopicMatcher("users.#"), Not(ExactMatcher("users.created"))),
+	}
+
+	topics := []string{
+		"", "users", "users.", "users.created", "users.india.created", "users.created.india",
+		"created", "users/created", "users/india/created", "users/", "$SYS", "$SYS/uptime", "$users",
+		"orders.created", "orders/created",
+	}
+
+	queueMatchers := make([]*queueMatcher, len(matchers))
+	idx := newSubscriptionIndex()
+	for i, m := range matchers {
+		queueMatchers[i] = &queueMatcher{id: uint64(i), matcher: MatchTopic(m)}
+		idx = idx.with(queueMatchers[i])
+	}
+
+	// The index should match exactly the subscribers, which would match when scanned.
+	assertMatches := func(idx *subscriptionIndex, included func(i int) bool) {
+		for _, topic := range topics {
+			expected := []uint64{}
+			for i, m := range matchers {
+				if included(i) && m.MatchString(topic) {
+					expected = append(expected, uint64(i))
+				}
+			}
+
+			if obtained := ids(idx.match(&Message{Topic: topic}, nil)); !reflect.DeepEqual(obtained, expected) {
+				t.Errorf("Invalid Match for %q: Expected: %v Obtained: %v", topic, expected, obtained)
+			}
+		}
+	}
+
+	assertMatches(idx, func(int) bool { return true })
+
+	// Excluding the subscribers derives a new index, leaving the original unchanged.
+	removed := idx
+	for i := 0; i < len(queueMatchers); i += 2 {
+		removed = removed.without(queueMatchers[i])
+	}
+
+	assertMatches(removed, func(i int) bool { return i%2 == 1 })
+	assertMatches(idx, func(int) bool { return true })
+
+	for i := 1; i < len(queueMatchers); i += 2 {
+		removed = removed.without(queueMatchers[i])
+	}
+
+	// The emptied nodes are removed.
+	for _, shard := range removed.exact {
+		if len(shard) != 0 {
+			t.Errorf("Invalid Exact Shard: Expected: empty Obtained: %v", shard)
+		}
+	}
+
+	if removed.topics != nil || removed.mqtt != nil || len(removed.others) != 0 {
+		t.Errorf("Invalid Index: Expected: empty Obtained: %+v", removed)
+	}
+}
+
+// ids returns the ids of the subscribers.
+func ids(subscribers []*queueMatcher) []uint64 {
+	ids := []uint64{}
+	for _, qm := range subscribers {
+		ids = append(ids, qm.id)
+	}
+
+	return ids
+}
+
+func TestSubscriptionIndexMessageMatcher(t *testing.T) {
+	idx := newSubscriptionIndex().
+		with(&queueMatcher{id: 0, matcher: HeaderMatcher{"region": "eu"}}).
+		with(&queueMatcher{id: 1, matcher: MatchTopic(ExactMatcher("users"))}).
+		with(&queueMatcher{id: 2, matcher: HeaderMatcher{"region": "us"}})
+
+	msg := &Message{Topic: "users", Headers: map[string]string{"region": "eu"}}
+	if obtained := ids(idx.match(msg, nil)); !reflect.DeepEqual(obtained, []uint64{0, 1}) {
+		t.Errorf("Invalid Match: Expected: %v Obtained: %v", []uint64{0, 1}, obtained)
+	}
+}
+
+func TestSortUnique(t *testing.T) {
+	qms := []*queueMatcher{{id: 0}, {id: 1}, {id: 2}, {id: 3}}
+
+	tests := []struct {
+		positions []int
+		expected  []uint64
+	}{
+		{positions: []int{}, expected: []uint64{}},
+		{positions: []int{1}, expected: []uint64{1}},
+		{positions: []int{3, 1, 2}, expected: []uint64{1, 2, 3}},
+		{positions: []int{2, 2, 1, 2, 1}, expected: []uint64{1, 2}},
+	}
+
+	for _, test := range tests {
+		subscribers := []*queueMatcher{}
+		for _, p := range test.positions {
+			subscribers = append(subscribers, qms[p])
+		}
+
+		if obtained := ids(sortUnique(subscribers)); !reflect.DeepEqual(obtained, test.expected) {
+			t.Errorf("Invalid Subscribers: Expected: %v Obtained: %v", test.expected, obtained)
+		}
+	}
+}
@@ -1,5 +1,11 @@
 package gomq
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
 // Matcher is the interface that wraps MatchString function.
 type Matcher interface {
 
@@ -7,6 +13,28 @@ type Matcher interface {
 	MatchString(string) bool
 }
 
+// MessageMatcher is the interface that wraps MatchMessage function.
+// Unlike Matcher, it matches on the whole message rather than just its topic.
+type MessageMatcher interface {
+
+	// MatchMessage returns true if the message matches.
+	MatchMessage(msg *Message) bool
+}
+
+// topicMatcher adapts a Matcher to MessageMatcher, by matching on the topic of messages.
+type topicMatcher struct {
+	Matcher
+}
+
+// MatchTopic returns a MessageMatcher, which matches the messages whose topic is matched by matcher.
+func MatchTopic(matcher Matcher) MessageMatcher {
+	return topicMatcher{Matcher: matcher}
+}
+
+func (tm topicMatcher) MatchMessage(msg *Message) bool {
+	return tm.MatchString(msg.Topic)
+}
+
 // ExactMatcher matches the pattern only if they are equal.
 type ExactMatcher string
 
@@ -15,3 +43,286 @@ type ExactMatcher string
 func (em ExactMatcher) MatchString(pattern string) bool {
 	return string(em) == pattern
 }
+
+// PrefixMatcher matches the topics starting with the prefix.
+type PrefixMatcher string
+
+// MatchString is the implementation of PrefixMatcher for Matcher interface.
+// It returns true if topic starts with PrefixMatcher.
+func (pm PrefixMatcher) MatchString(topic string) bool {
+	return strings.HasPrefix(topic, string(pm))
+}
+
+// SuffixMatcher matches the topics ending with the suffix.
+type SuffixMatcher string
+
+// MatchString is the implementation of SuffixMatcher for Matcher interface.
+// It returns true if topic ends with SuffixMatcher.
+func (sm SuffixMatcher) MatchString(topic string) bool {
+	return strings.HasSuffix(topic, string(sm))
+}
+
+// anyOf matches the topics matched by any of its matchers.
+type anyOf []Matcher
+
+// AnyOf returns a Matcher, which matches the topics matched by any of the matchers.
+// Hence a single subscription can listen to several patterns, receiving a message once even if the patterns overlap.
+// With no matchers, it matches no topic.
+func AnyOf(matchers ...Matcher) Matcher {
+	return anyOf(matchers)
+}
+
+func (am anyOf) MatchString(topic string) bool {
+	for _, m := range am {
+		if m.MatchString(topic) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allOf matches the topics matched by all of its matchers.
+type allOf []Matcher
+
+// AllOf returns a Matcher, which matches the topics matched by all of the matchers.
+// With no matchers, it matches every topic.
+func AllOf(matchers ...Matcher) Matcher {
+	return allOf(matchers)
+}
+
+func (am allOf) MatchString(topic string) bool {
+	for _, m := range am {
+		if !m.MatchString(topic) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// not matches the topics not matched by its matcher.
+type not struct {
+	matcher Matcher
+}
+
+// Not returns a Matcher, which matches the topics not matched by matcher.
+// For example, `AllOf(MustTopicMatcher("users.#"), Not(ExactMatcher("users.deleted")))` excludes a topic from a pattern.
+func Not(matcher Matcher) Matcher {
+	return not{matcher: matcher}
+}
+
+func (nm not) MatchString(topic string) bool {
+	return !nm.matcher.MatchString(topic)
+}
+
+// TopicMatcher matches dot separated topics against a pattern, similar to RabbitMQ topic exchanges.
+//
+// In the pattern, `*` matches exactly one word and `#` matches zero or more words.
+// For example, `users.*.created` matches "users.india.created" and `users.#` matches "users" & "users.india.created".
+type TopicMatcher struct {
+	pattern string
+	words   []string
+}
+
+// NewTopicMatcher creates a TopicMatcher for the pattern.
+// It returns an error if the pattern is empty, or a wildcard is part of a word.
+func NewTopicMatcher(pattern string) (*TopicMatcher, error) {
+	if pattern == "" {
+		return nil, errors.New("gomq: empty topic pattern")
+	}
+
+	words := []string{}
+	for _, word := range strings.Split(pattern, ".") {
+		if word != "*" && word != "#" && strings.ContainsAny(word, "*#") {
+			return nil, fmt.Errorf("gomq: invalid word %q in topic pattern %q, wildcards should be a whole word", word, pattern)
+		}
+
+		// Consecutive `#` are same as a single `#`.
+		if word == "#" && len(words) > 0 && words[len(words)-1] == "#" {
+			continue
+		}
+
+		words = append(words, word)
+	}
+
+	return &TopicMatcher{pattern: pattern, words: words}, nil
+}
+
+// MustTopicMatcher is similar to NewTopicMatcher, but panics if the pattern is invalid.
+func MustTopicMatcher(pattern string) *TopicMatcher {
+	tm, err := NewTopicMatcher(pattern)
+	if err != nil {
+		panic(err)
+	}
+
+	return tm
+}
+
+// String returns the pattern of TopicMatcher.
+func (tm *TopicMatcher) String() string {
+	return tm.pattern
+}
+
+// MatchString is the implementation of TopicMatcher for Matcher interface.
+// It returns true if the topic matches the pattern.
+func (tm *TopicMatcher) MatchString(topic string) bool {
+	return tm.match(0, topic, 0)
+}
+
+// match returns true if the words of topic starting at offset, matches the pattern words starting at i.
+// An offset beyond the length of topic indicates that no words are left.
+func (tm *TopicMatcher) match(i int, topic string, offset int) bool {
+	for ; i < len(tm.words); i++ {
+		word := tm.words[i]
+
+		if word == "#" {
+			if i == len(tm.words)-1 {
+				return true
+			}
+
+			// Tries to match rest of the pattern, after skipping zero or more words.
+			for ; offset <= len(topic); offset = nextLevel(topic, offset, '.') {
+				if tm.match(i+1, topic, offset) {
+					return true
+				}
+			}
+
+			return tm.match(i+1, topic, offset)
+		}
+
+		if offset > len(topic) {
+			return false
+		}
+
+		next := nextLevel(topic, offset, '.')
+		if word != "*" && word != topic[offset:next-1] {
+			return false
+		}
+
+		offset = next
+	}
+
+	return offset > len(topic)
+}
+
+// MQTTMatcher matches slash separated topics against a topic filter, as per MQTT 3.1.1.
+//
+// In the filter, `+` matches exactly one level and `#` matches the parent level & any number of child levels.
+// For example, `site/+/sensor/#` matches "site/1/sensor", "site/1/sensor/temperature" and so on.
+// Topics starting with `$` are not matched by filters starting with a wildcard.
+type MQTTMatcher struct {
+	filter string
+	levels []string
+}
+
+// NewMQTTMatcher creates a MQTTMatcher for the topic filter.
+// It returns an error if the filter is empty, a wildcard is part of a level or `#` is not the last level.
+func NewMQTTMatcher(filter string) (*MQTTMatcher, error) {
+	if filter == "" {
+		return nil, errors.New("gomq: empty topic filter")
+	}
+
+	if strings.IndexByte(filter, 0) >= 0 {
+		return nil, fmt.Errorf("gomq: topic filter %q contains null character", filter)
+	}
+
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		if level != "+" && level != "#" && strings.ContainsAny(level, "+#") {
+			return nil, fmt.Errorf("gomq: invalid level %q in topic filter %q, wildcards should be a whole level", level, filter)
+		}
+
+		if level == "#" && i != len(levels)-1 {
+			return nil, fmt.Errorf("gomq: invalid topic filter %q, `#` should be the last level", filter)
+		}
+	}
+
+	return &MQTTMatcher{filter: filter, levels: levels}, nil
+}
+
+// MustMQTTMatcher is similar to NewMQTTMatcher, but panics if the filter is invalid.
+func MustMQTTMatcher(filter string) *MQTTMatcher {
+	mm, err := NewMQTTMatcher(filter)
+	if err != nil {
+		panic(err)
+	}
+
+	return mm
+}
+
+// String returns the topic filter of MQTTMatcher.
+func (mm *MQTTMatcher) String() string {
+	return mm.filter
+}
+
+// MatchString is the implementation of MQTTMatcher for Matcher interface.
+// It returns true if the topic matches the topic filter.
+func (mm *MQTTMatcher) MatchString(topic string) bool {
+	if strings.HasPrefix(topic, "$") && (mm.levels[0] == "+" || mm.levels[0] == "#") {
+		return false
+	}
+
+	// An offset beyond the length of topic indicates that no levels are left.
+	offset := 0
+	for _, level := range mm.levels {
+		if level == "#" {
+			return true
+		}
+
+		if offset > len(topic) {
+			return false
+		}
+
+		next := nextLevel(topic, offset, '/')
+		if level != "+" && level != topic[offset:next-1] {
+			return false
+		}
+
+		offset = next
+	}
+
+	return offset > len(topic)
+}
+
+// nextLevel returns the offset of the level after the level at offset, where levels are separated by sep.
+// If there are no more levels, it returns an offset beyond the length of topic.
+func nextLevel(topic string, offset int, sep byte) int {
+	if end := strings.IndexByte(topic[offset:], sep); end >= 0 {
+		return offset + end + 1
+	}
+
+	return len(topic) + 1
+}
+
+// HeaderMatchKey is the key of HeaderMatcher, which decides whether all or any of the headers should match.
+const HeaderMatchKey = "x-match"
+
+// HeaderMatcher matches the messages on their headers regardless of the topic, similar to RabbitMQ headers exchanges.
+//
+// The value of HeaderMatchKey decides how the headers are matched,
+// "all" requires all the headers to be equal, where as "any" requires at least one of them.
+// If not set, it is considered as "all".
+// Keys starting with "x-" are not matched.
+//
+// For example, `HeaderMatcher{"region": "eu", "x-match": "all"}` matches the messages with "region" header as "eu".
+type HeaderMatcher map[string]string
+
+// MatchMessage is the implementation of HeaderMatcher for MessageMatcher interface.
+// It returns true if the headers of message match.
+func (hm HeaderMatcher) MatchMessage(msg *Message) bool {
+	matchAny := hm[HeaderMatchKey] == "any"
+
+	for key, value := range hm {
+		if strings.HasPrefix(key, "x-") {
+			continue
+		}
+
+		actual, ok := msg.Headers[key]
+		if matched := ok && actual == value; matched == matchAny {
+			return matchAny
+		}
+	}
+
+	return !matchAny
+}
@@ -0,0 +1,164 @@
+package gomq
+
+import "testing"
+
+func TestTopicMatcher(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		match   bool
+	}{
+		{pattern: "users.created", topic: "users.created", match: true},
+		{pattern: "users.created", topic: "users.deleted", match: false},
+		{pattern: "users.created", topic: "users.created.india", match: false},
+		{pattern: "users.*", topic: "users.created", match: true},
+		{pattern: "users.*", topic: "users", match: false},
+		{pattern: "users.*", topic: "users.created.india", match: false},
+		{pattern: "*.created", topic: "users.created", match: true},
+		{pattern: "users.*.created", topic: "users.india.created", match: true},
+		{pattern: "users.#", topic: "users", match: true},
+		{pattern: "users.#", topic: "users.created", match: true},
+		{pattern: "users.#", topic: "users.india.created", match: true},
+		{pattern: "users.#", topic: "orders.created", match: false},
+		{pattern: "#", topic: "users.india.created", match: true},
+		{pattern: "#", topic: "", match: true},
+		{pattern: "#.created", topic: "created", match: true},
+		{pattern: "#.created", topic: "users.india.created", match: true},
+		{pattern: "#.created", topic: "users.created.india", match: false},
+		{pattern: "users.#.created", topic: "users.created", match: true},
+		{pattern: "users.#.created", topic: "users.india.mumbai.created", match: true},
+		{pattern: "users.#.#.created", topic: "users.india.created", match: true},
+		{pattern: "users.#.*", topic: "users", match: false},
+		{pattern: "users.#.*", topic: "users.created", match: true},
+		{pattern: "*.*", topic: "users.", match: true},
+		{pattern: "users.#", topic: "users.", match: true},
+		{pattern: "users.created", topic: "users.created.", match: false},
+		{pattern: "users.*", topic: "user.created", match: false},
+	}
+
+	for _, test := range tests {
+		if match := MustTopicMatcher(test.pattern).MatchString(test.topic); match != test.match {
+			t.Errorf("Invalid Match for %q on %q: Expected: %v Obtained: %v", test.pattern, test.topic, test.match, match)
+		}
+	}
+}
+
+func TestTopicMatcherValidation(t *testing.T) {
+	for _, pattern := range []string{"", "users.*s", "users#", "users.#.cr*ated"} {
+		if _, err := NewTopicMatcher(pattern); err == nil {
+			t.Errorf("Invalid pattern %q should return an error", pattern)
+		}
+	}
+
+	if tm, err := NewTopicMatcher("users.#"); err != nil || tm.String() != "users.#" {
+		t.Errorf("Invalid TopicMatcher: Expected: users.# <nil> Obtained: %v %v", tm, err)
+	}
+}
+
+func TestMQTTMatcher(t *testing.T) {
+	tests := []struct {
+		filter string
+		topic  string
+		match  bool
+	}{
+		{filter: "sport/tennis/player1", topic: "sport/tennis/player1", match: true},
+		{filter: "sport/tennis/player1", topic: "sport/tennis/player2", match: false},
+		{filter: "sport/tennis/player1/#", topic: "sport/tennis/player1", match: true},
+		{filter: "sport/tennis/player1/#", topic: "sport/tennis/player1/ranking", match: true},
+		{filter: "sport/tennis/player1/#", topic: "sport/tennis/player1/score/wimbledon", match: true},
+		{filter: "sport/#", topic: "sport", match: true},
+		{filter: "sport/#", topic: "sports", match: false},
+		{filter: "#", topic: "sport/tennis", match: true},
+		{filter: "sport/tennis/+", topic: "sport/tennis/player1", match: true},
+		{filter: "sport/tennis/+", topic: "sport/tennis/player1/ranking", match: false},
+		{filter: "sport/+", topic: "sport", match: false},
+		{filter: "sport/+", topic: "sport/", match: true},
+		{filter: "+/+", topic: "/finance", match: true},
+		{filter: "/+", topic: "/finance", match: true},
+		{filter: "+", topic: "/finance", match: false},
+		{filter: "site/+/sensor/#", topic: "site/1/sensor", match: true},
+		{filter: "site/+/sensor/#", topic: "site/1/sensor/temperature", match: true},
+		{filter: "site/+/sensor/#", topic: "site/1/actuator/valve", match: false},
+		{filter: "#", topic: "$SYS/monitor/clients", match: false},
+		{filter: "+/monitor/clients", topic: "$SYS/monitor/clients", match: false},
+		{filter: "$SYS/#", topic: "$SYS/monitor/clients", match: true},
+		{filter: "$SYS/monitor/+", topic: "$SYS/monitor/clients", match: true},
+	}
+
+	for _, test := range tests {
+		if match := MustMQTTMatcher(test.filter).MatchString(test.topic); match != test.match {
+			t.Errorf("Invalid Match for %q on %q: Expected: %v Obtained: %v", test.filter, test.topic, test.match, match)
+		}
+	}
+}
+
+func TestMQTTMatcherValidation(t *testing.T) {
+	for _, filter := range []string{"", "sport/tennis#", "sport/tennis/#/ranking", "sport+", "sport/+tennis", "sport/\x00"} {
+		if _, err := NewMQTTMatcher(filter); err == nil {
+			t.Errorf("Invalid filter %q should return an error", filter)
+		}
+	}
+
+	if mm, err := NewMQTTMatcher("sport/+/#"); err != nil || mm.String() != "sport/+/#" {
+		t.Errorf("Invalid MQTTMatcher: Expected: sport/+/# <nil> Obtained: %v %v", mm, err)
+	}
+}
+
+func TestCompositeMatcher(t *testing.T) {
+	tests := []struct {
+		name    string
+		matcher Matcher
+		topic   string
+		match   bool
+	}{
+		{name: "Prefix", matcher: PrefixMatcher("users."), topic: "users.created", match: true},
+		{name: "Prefix", matcher: PrefixMatcher("users."), topic: "orders.created", match: false},
+		{name: "Suffix", matcher: SuffixMatcher(".created"), topic: "users.created", match: true},
+		{name: "Suffix", matcher: SuffixMatcher(".created"), topic: "users.deleted", match: false},
+		{name: "AnyOf", matcher: AnyOf(ExactMatcher("a"), MustTopicMatcher("b.#")), topic: "a", match: true},
+		{name: "AnyOf", matcher: AnyOf(ExactMatcher("a"), MustTopicMatcher("b.#")), topic: "b.c", match: true},
+		{name: "AnyOf", matcher: AnyOf(ExactMatcher("a"), MustTopicMatcher("b.#")), topic: "c", match: false},
+		{name: "AnyOf", matcher: AnyOf(), topic: "a", match: false},
+		{name: "AllOf", matcher: AllOf(PrefixMatcher("users."), SuffixMatcher(".created")), topic: "users.india.created", match: true},
+		{name: "AllOf", matcher: AllOf(PrefixMatcher("users."), SuffixMatcher(".created")), topic: "users.india.deleted", match: false},
+		{name: "AllOf", matcher: AllOf(), topic: "a", match: true},
+		{name: "Not", matcher: Not(ExactMatcher("a")), topic: "a", match: false},
+		{name: "Not", matcher: Not(ExactMatcher("a")), topic: "b", match: true},
+		{name: "Exclude", matcher: AllOf(MustTopicMatcher("users.#"), Not(ExactMatcher("users.deleted"))), topic: "users.created", match: true},
+		{name: "Exclude", matcher: AllOf(MustTopicMatcher("users.#"), Not(ExactMatcher("users.deleted"))), topic: "users.deleted", match: false},
+	}
+
+	for _, test := range tests {
+		if match := test.matcher.MatchString(test.topic); match != test.match {
+			t.Errorf("Invalid %s Match on %q: Expected: %v Obtained: %v", test.name, test.topic, test.match, match)
+		}
+	}
+}
+
+func TestHeaderMatcher(t *testing.T) {
+	headers := map[string]string{"region": "eu", "format": "json"}
+
+	tests := []struct {
+		matcher HeaderMatcher
+		match   bool
+	}{
+		{matcher: HeaderMatcher{"region": "eu"}, match: true},
+		{matcher: HeaderMatcher{"region": "eu", "format": "json", "x-match": "all"}, match: true},
+		{matcher: HeaderMatcher{"region": "eu", "format": "xml"}, match: false},
+		{matcher: HeaderMatcher{"region": "eu", "format": "xml", "x-match": "any"}, match: true},
+		{matcher: HeaderMatcher{"region": "us", "format": "xml", "x-match": "any"}, match: false},
+		{matcher: HeaderMatcher{"source": "", "x-match": "all"}, match: false},
+		{matcher: HeaderMatcher{"x-match": "all"}, match: true},
+		{matcher: HeaderMatcher{"x-match": "any"}, match: false},
+	}
+
+	for _, test := range tests {
+		if match := test.matcher.MatchMessage(&Message{Headers: headers}); match != test.match {
+			t.Errorf("Invalid Match for %v: Expected: %v Obtained: %v", test.matcher, test.match, match)
+		}
+	}
+
+	if !MatchTopic(ExactMatcher("users")).MatchMessage(&Message{Topic: "users"}) {
+		t.Errorf("Invalid Match for MatchTopic: Expected: true Obtained: false")
+	}
+}
@@ -0,0 +1,67 @@
+package gomq
+
+import (
+	"context"
+	"time"
+)
+
+// Message is the envelope in which data is published to a topic.
+//
+// A published message is shared by all its subscribers,
+// hence it shouldn't be modified once published.
+type Message struct {
+
+	// ID uniquely identifies the message within its broker.
+	// If empty, it is generated by the broker on publishing.
+	ID string
+
+	// Topic is the topic to which the message is published.
+	Topic string
+
+	// Timestamp is the time at which the message is published.
+	// If zero, it is set by the broker on publishing.
+	Timestamp time.Time
+
+	// Headers holds the metadata of the message.
+	Headers map[string]string
+
+	// Payload is the data being published.
+	Payload interface{}
+
+	// TTL is the duration after its Timestamp, beyond which the message is discarded rather than being polled.
+	// A zero TTL never expires the message, unless the subscription has a TTL.
+	TTL time.Duration
+
+	// Priority orders the message in the subscriptions created with WithPriority,
+	// the message of higher priority is polled ahead of the messages pending in the subscription.
+	Priority int
+
+	// Retain makes the broker keep the message as the last value of its topic,
+	// which is delivered to the subscriptions created later, matching the topic.
+	// A retained message with nil Payload clears the message retained for its topic.
+	Retain bool
+
+	// Offset is the position of the message in the history of its topic, starting from 0.
+	// It is set by the broker on publishing, only for the topics configured by WithHistory.
+	Offset uint64
+}
+
+// expired returns true, if the message has expired by now.
+// The subscription's ttl applies along with the TTL of the message, whichever expires earlier.
+func (m *Message) expired(ttl time.Duration, now time.Time) bool {
+	if ttl <= 0 || (m.TTL > 0 && m.TTL < ttl) {
+		ttl = m.TTL
+	}
+
+	return ttl > 0 && now.Sub(m.Timestamp) > ttl
+}
+
+// MessagePoller is the interface that wraps PollMessage function.
+type MessagePoller interface {
+
+	// PollMessage is similar to Poll, but returns the whole message rather than its payload.
+	PollMessage() (*Message, bool)
+
+	// PollMessageContext is similar to PollContext, but returns the whole message rather than its payload.
+	PollMessageContext(ctx context.Context) (*Message, error)
+}
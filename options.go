@@ -0,0 +1,216 @@
+package gomq
+
+import (
+	"time"
+
+	"github.com/RohanPoojary/gomq/queue"
+)
+
+// SubscribeOption configures the Subscription created by SubscribeWithOptions.
+type SubscribeOption func(*subscribeConfig)
+
+type subscribeConfig struct {
+	name            string
+	group           string
+	durable         bool
+	ackDeadline     time.Duration
+	maxDeliveries   int
+	deadLetterTopic string
+	filter          MessageMatcher
+	ttl             time.Duration
+	queue           queue.Options
+	start           StartPosition
+}
+
+func newSubscribeConfig(opts []SubscribeOption) subscribeConfig {
+	cfg := subscribeConfig{queue: queue.Options{Policy: queue.Block}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+// WithName names the subscription, which is returned by Subscription.Name.
+// If not provided, the broker generates a unique name for the subscription.
+func WithName(name string) SubscribeOption {
+	return func(cfg *subscribeConfig) {
+		cfg.name = name
+	}
+}
+
+// WithGroup makes the subscription a member of the consumer group.
+//
+// All the members of a group share the same queue, hence a message is delivered to only one of them.
+// Subscribing to an existing group adds a member to it, the group retains the matcher and options of its first member.
+// If not named by WithName, the subscription is named after the group.
+func WithGroup(group string) SubscribeOption {
+	return func(cfg *subscribeConfig) {
+		cfg.group = group
+	}
+}
+
+// WithDurable makes the named subscription durable.
+//
+// Subscribing again with the name of an existing durable subscription, attaches to it rather than creating a new one.
+// Hence, the data published in the meantime is not lost.
+// The existing subscription retains its matcher and options.
+// It is kept attached to the broker once its Subscriptions are detached by Subscription.Detach,
+// and it is removed once any of its Subscriptions is unsubscribed.
+//
+// It has no effect unless used along with WithName.
+func WithDurable() SubscribeOption {
+	return func(cfg *subscribeConfig) {
+		cfg.durable = true
+	}
+}
+
+// WithAckDeadline enables acknowledgement mode for the subscription.
+//
+// The messages polled by PollDelivery are to be acknowledged within deadline,
+// else they are redelivered to the head of the subscription.
+// The messages read by other Poll functions are considered acknowledged once polled.
+func WithAckDeadline(deadline time.Duration) SubscribeOption {
+	return func(cfg *subscribeConfig) {
+		cfg.ackDeadline = deadline
+	}
+}
+
+// WithMaxDeliveries limits the count of times a message is delivered in acknowledgement mode.
+// Once a message has been delivered max times, it is dead lettered rather than being redelivered.
+//
+// A max less than 1 redelivers the message any number of times, which is the default.
+func WithMaxDeliveries(max int) SubscribeOption {
+	return func(cfg *subscribeConfig) {
+		cfg.maxDeliveries = max
+	}
+}
+
+// WithDeadLetter publishes the messages which the subscription couldn't process, to topic on the same broker.
+//
+// A message is dead lettered, when it is rejected or delivered the maximum number of times.
+// The dead lettered message holds the reason & its origin in its headers.
+// Without a dead letter topic, such messages are discarded.
+//
+// The dead letters are published as any other message, hence they are validated by the Registry of the broker.
+// The subscriber dead lettering a message never waits for space in the subscriptions of the dead letter topic,
+// hence the dead letters beyond the capacity of a subscription with queue.Block policy are dropped.
+// For AsyncBroker, they are queued & published along with the other data instead.
+func WithDeadLetter(topic string) SubscribeOption {
+	return func(cfg *subscribeConfig) {
+		cfg.deadLetterTopic = topic
+	}
+}
+
+// WithFilter publishes to the subscription only the messages matched by filter, such as a Filter or HeaderMatcher.
+// The messages are filtered while publishing, hence the filtered out messages never reach the subscription.
+//
+// For example, `WithFilter(MustFilter("priority > 3 AND region IN ('eu', 'us')"))`.
+func WithFilter(filter MessageMatcher) SubscribeOption {
+	return func(cfg *subscribeConfig) {
+		cfg.filter = filter
+	}
+}
+
+// WithTTL discards the messages held by the subscription for longer than ttl after being published,
+// rather than delivering them stale. It applies along with the TTL of the messages, whichever expires earlier.
+//
+// The expired messages are counted by Subscription.Stats, and dead lettered if WithDeadLetter is set.
+func WithTTL(ttl time.Duration) SubscribeOption {
+	return func(cfg *subscribeConfig) {
+		cfg.ttl = ttl
+	}
+}
+
+// WithCapacity bounds the subscription to hold at most capacity unpolled data.
+// Once full, the published data is handled based on the overflow policy, which defaults to queue.Block.
+//
+// A capacity less than 1 makes the subscription unbounded, which is the default.
+func WithCapacity(capacity int) SubscribeOption {
+	return func(cfg *subscribeConfig) {
+		cfg.queue.Capacity = capacity
+	}
+}
+
+// WithOverflowPolicy sets how a bounded subscription handles data published while it is full.
+// It has no effect unless used along with WithCapacity.
+func WithOverflowPolicy(policy queue.OverflowPolicy) SubscribeOption {
+	return func(cfg *subscribeConfig) {
+		cfg.queue.Policy = policy
+	}
+}
+
+// WithPrefetch sets the count of data handed over ahead of polling, which defaults to 1.
+// A larger prefetch suits subscriptions polled by many routines at a high rate.
+func WithPrefetch(prefetch int) SubscribeOption {
+	return func(cfg *subscribeConfig) {
+		cfg.queue.Prefetch = prefetch
+	}
+}
+
+// WithPriority makes the subscription poll the pending message of the highest Message.Priority first,
+// the messages of the same priority are polled in the order they are published.
+// Hence, urgent messages bypass a backlog of the less urgent ones.
+func WithPriority() SubscribeOption {
+	return func(cfg *subscribeConfig) {
+		cfg.queue.Priority = func(val interface{}) int { return message(val).Priority }
+	}
+}
+
+// WithStartFrom makes the subscription start from the position in the history of its topics,
+// hence the messages published before subscribing are replayed ahead of the ones published later.
+// The history is kept only for the topics configured by WithHistory, the subscription starts from now for other topics.
+//
+// The replayed messages are pushed without waiting for space in a subscription bounded by WithCapacity,
+// hence the messages beyond its capacity are dropped, unless its overflow policy is queue.DropOldest.
+func WithStartFrom(pos StartPosition) SubscribeOption {
+	return func(cfg *subscribeConfig) {
+		cfg.start = pos
+	}
+}
+
+// BrokerOption configures the Broker created by NewBrokerWithOptions or NewAsyncBrokerWithOptions.
+type BrokerOption func(*brokerConfig)
+
+type brokerConfig struct {
+	registry  *Registry
+	histories []historyConfig
+}
+
+func newBrokerConfig(opts []BrokerOption) brokerConfig {
+	cfg := brokerConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+// WithRegistry makes the broker reject the payloads not of the type registered in registry for their topic.
+// PublishE & PublishMessageE return an error wrapping ErrTypeMismatch for such payloads.
+func WithRegistry(registry *Registry) BrokerOption {
+	return func(cfg *brokerConfig) {
+		cfg.registry = registry
+	}
+}
+
+// WithHistory makes the broker keep the history of the topics matched by topic, which can be replayed by WithStartFrom.
+// The history of every topic is kept separately, holding its last limit messages published within the retention.
+// If a topic is matched by many histories, the first one applies.
+//
+// The history of a topic is removed once its messages are all evicted by the retention,
+// after which the offsets of the topic start again from 0.
+// Hence, for many distinct topics such as a topic per entity, the history should be limited by retention,
+// as without it, the last limit messages of every topic ever published are kept.
+//
+// A limit less than 1 doesn't limit the count of messages, and a retention less than 1 doesn't limit their age.
+// It panics, if neither is limited.
+func WithHistory(topic Matcher, limit int, retention time.Duration) BrokerOption {
+	if limit < 1 && retention <= 0 {
+		panic("gomq: history should be limited by either count or retention")
+	}
+
+	return func(cfg *brokerConfig) {
+		cfg.histories = append(cfg.histories, historyConfig{matcher: topic, limit: limit, retention: retention})
+	}
+}
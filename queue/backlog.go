@@ -0,0 +1,111 @@
+package queue
+
+import "container/heap"
+
+// backlog holds the values pending in the queue, in the order they are to be polled.
+// It is accessed only by manage.
+type backlog[T any] interface {
+	push(value T)
+
+	// pushFront adds the value to be polled ahead of the values pushed.
+	pushFront(value T)
+
+	peek() T
+	pop() T
+	len() int
+}
+
+// fifo polls the values in the order they are pushed.
+type fifo[T any] struct {
+	values []T
+}
+
+func (f *fifo[T]) push(value T) {
+	f.values = append(f.values, value)
+}
+
+func (f *fifo[T]) pushFront(value T) {
+	f.values = append([]T{value}, f.values...)
+}
+
+func (f *fifo[T]) peek() T {
+	return f.values[0]
+}
+
+func (f *fifo[T]) pop() T {
+	var zero T
+
+	value := f.values[0]
+
+	// The removed value is replaced, so that it can be garbage collected.
+	f.values[0] = zero
+	f.values = f.values[1:]
+
+	return value
+}
+
+func (f *fifo[T]) len() int {
+	return len(f.values)
+}
+
+// prioritized polls the values of higher priority first, and the values of the same priority in the order they are pushed.
+type prioritized[T any] struct {
+	items    priorityHeap[T]
+	priority func(T) int
+
+	// seq orders the values of the same priority, the values pushed to the front take a negative seq.
+	seq, frontSeq int64
+}
+
+type priorityItem[T any] struct {
+	value    T
+	priority int
+	seq      int64
+}
+
+// priorityHeap implements heap.Interface.
+type priorityHeap[T any] []priorityItem[T]
+
+func (h priorityHeap[T]) Len() int { return len(h) }
+
+func (h priorityHeap[T]) Less(i, j int) bool {
+	if h[i].priority == h[j].priority {
+		return h[i].seq < h[j].seq
+	}
+
+	return h[i].priority > h[j].priority
+}
+
+func (h priorityHeap[T]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *priorityHeap[T]) Push(x interface{}) { *h = append(*h, x.(priorityItem[T])) }
+
+func (h *priorityHeap[T]) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = priorityItem[T]{}
+	*h = old[:len(old)-1]
+	return item
+}
+
+func (p *prioritized[T]) push(value T) {
+	p.seq++
+	heap.Push(&p.items, priorityItem[T]{value: value, priority: p.priority(value), seq: p.seq})
+}
+
+func (p *prioritized[T]) pushFront(value T) {
+	p.frontSeq--
+	heap.Push(&p.items, priorityItem[T]{value: value, priority: p.priority(value), seq: p.frontSeq})
+}
+
+func (p *prioritized[T]) peek() T {
+	return p.items[0].value
+}
+
+func (p *prioritized[T]) pop() T {
+	return heap.Pop(&p.items).(priorityItem[T]).value
+}
+
+func (p *prioritized[T]) len() int {
+	return len(p.items)
+}
@@ -96,3 +96,59 @@ func BenchmarkPollWithAsyncPublish(b *testing.B) {
 
 	closeCh <- true
 }
+
+func BenchmarkPollBatchWithAsyncPublish(b *testing.B) {
+
+	queue := NewQueue()
+	defer queue.Close(0)
+
+	closeCh := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-closeCh:
+				return
+			default:
+				queue.Push(rand.Intn(100000))
+			}
+		}
+	}()
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			queue.PollBatch(100, 0)
+		}
+	})
+
+	closeCh <- true
+}
+
+func BenchmarkTypedPushPoll(b *testing.B) {
+
+	// Unlike Queue, the values of TypedQueue aren't boxed in an interface,
+	// hence pushing an int doesn't allocate for boxing it.
+
+	b.Run("Queue", func(b *testing.B) {
+		queue := NewQueue()
+		defer queue.Close(0)
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			queue.Push(i + 1000)
+			queue.Poll()
+		}
+	})
+
+	b.Run("TypedQueue", func(b *testing.B) {
+		queue := NewTypedQueue[int]()
+		defer queue.Close(0)
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			queue.Push(i + 1000)
+			queue.Poll()
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package queue
+
+import (
+	"context"
+	"errors"
+)
+
+// OverflowPolicy decides how a bounded queue handles a push, when it is full.
+type OverflowPolicy int
+
+const (
+	// Block makes Push wait until a value is polled from the queue.
+	Block OverflowPolicy = iota
+
+	// DropNewest discards the value being pushed.
+	DropNewest
+
+	// DropOldest discards the top most element of the queue, to make space for the value being pushed.
+	DropOldest
+
+	// Reject makes Push return ErrFull.
+	Reject
+)
+
+// errDropped is returned by acquire, when the value being pushed is to be discarded silently.
+var errDropped = errors.New("queue: dropped")
+
+// NewBoundedQueue creates a new thread safe queue, which holds at most capacity elements.
+// Once the queue is full, further pushes are handled based on policy.
+//
+// It panics if capacity is less than 1.
+func NewBoundedQueue(capacity int, policy OverflowPolicy) Queue {
+	if capacity < 1 {
+		panic("queue: capacity should be positive")
+	}
+
+	return NewQueueWithOptions(Options{Capacity: capacity, Policy: policy})
+}
+
+// acquire reserves space for a value to be pushed, based on the overflow policy.
+// Waiting for the space is stopped once ctx is done.
+func (q *queue[T]) acquire(ctx context.Context, policy OverflowPolicy) error {
+	if q.slots == nil {
+		return nil
+	}
+
+	for {
+		select {
+		case q.slots <- struct{}{}:
+			return nil
+		default:
+		}
+
+		switch policy {
+		case DropNewest:
+			return errDropped
+		case Reject:
+			return ErrFull
+		case DropOldest:
+			// The evicted value releases its space, hence retried to acquire.
+			if values, closed := q.take(1); closed {
+				return ErrClosed
+			} else if len(values) > 0 {
+				continue
+			}
+		}
+
+		// Either the policy is to block or all the space is held by values in transit.
+		select {
+		case q.slots <- struct{}{}:
+			return nil
+		case <-q.closing:
+			return ErrClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release frees the space held by n polled values.
+func (q *queue[T]) release(n int) {
+	if q.slots == nil {
+		return
+	}
+
+	for i := 0; i < n; i++ {
+		<-q.slots
+	}
+}
@@ -0,0 +1,24 @@
+package queue
+
+// Prioritized is implemented by the values pushed to a queue created by NewPriorityQueue.
+type Prioritized interface {
+
+	// Priority returns the priority of the value, a value of higher priority is polled first.
+	Priority() int
+}
+
+// NewPriorityQueue creates a new thread safe queue, which polls the value of the highest priority first.
+// The values of the same priority are polled in the order they are pushed.
+//
+// The priority of a value is obtained through Prioritized, the values not implementing it are of priority 0.
+func NewPriorityQueue() Queue {
+	return NewQueueWithOptions(Options{Priority: priorityOf})
+}
+
+func priorityOf(value interface{}) int {
+	if p, ok := value.(Prioritized); ok {
+		return p.Priority()
+	}
+
+	return 0
+}
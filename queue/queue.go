@@ -1,15 +1,48 @@
 package queue
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"time"
 )
 
+var (
+	// ErrClosed is returned when polling from a queue, which is closed and has no pending data.
+	ErrClosed = errors.New("queue: closed")
+
+	// ErrTimeout is returned by PollTimeout, when no data is available within the timeout.
+	ErrTimeout = errors.New("queue: poll timed out")
+
+	// ErrFull is returned by Push, when a bounded queue with Reject policy is full.
+	ErrFull = errors.New("queue: full")
+)
+
 // Queue provides thread safe queue functions.
-type Queue interface {
+// It holds values of any type, hence the polled values are to be type asserted.
+type Queue = TypedQueue[interface{}]
+
+// TypedQueue provides thread safe queue functions, for values of type T.
+// Unlike Queue, the values are held without being boxed in an interface.
+type TypedQueue[T any] interface {
 
 	// Push pushes value to the queue.
-	Push(value interface{})
+	//
+	// For bounded queues, a full queue is handled based on its OverflowPolicy.
+	// In case of closed queue, ErrClosed is returned.
+	Push(value T) error
+
+	// PushContext is similar to Push, but a push waiting for space in a full queue returns once ctx is done.
+	//
+	// If ctx is cancelled or its deadline is exceeded, then ctx.Err() is returned.
+	PushContext(ctx context.Context, value T) error
+
+	// PushFront pushes value to the head of the queue, hence it is polled next.
+	// It is meant for returning a polled value back to the queue.
+	//
+	// For bounded queues, it waits for space regardless of the OverflowPolicy.
+	// In case of closed queue, ErrClosed is returned.
+	PushFront(value T) error
 
 	// Poll pops the top most element of queue.
 	// If not data is present in the queue, then ok will be false.
@@ -17,7 +50,34 @@ type Queue interface {
 	// This is blocking call,
 	// Hence, it will wait till a queue is non empty.
 	// In case of closed queue, Ok will be false.
-	Poll() (value interface{}, ok bool)
+	Poll() (value T, ok bool)
+
+	// PollContext is similar to Poll, but returns once ctx is done.
+	//
+	// If ctx is cancelled or its deadline is exceeded, then ctx.Err() is returned.
+	// In case of closed queue, ErrClosed is returned.
+	PollContext(ctx context.Context) (value T, err error)
+
+	// TryPoll is the non blocking variant of Poll.
+	// If data is available, then it returns the value and ok as true.
+	//
+	// If the queue is empty, then it returns immediately with ok as false.
+	// In case of closed queue, closed will be true.
+	TryPoll() (value T, ok bool, closed bool)
+
+	// PollTimeout is similar to Poll, but waits at most for timeout.
+	//
+	// If no data is available within timeout, then ErrTimeout is returned.
+	// In case of closed queue, ErrClosed is returned.
+	PollTimeout(timeout time.Duration) (value T, err error)
+
+	// PollBatch pops up to max elements of the queue at once.
+	//
+	// This is blocking call until the first element is available,
+	// later elements are gathered until either max elements are obtained or wait elapses.
+	// A max less than 1 is considered as 1.
+	// In case of closed queue, Ok will be false.
+	PollBatch(max int, wait time.Duration) (values []T, ok bool)
 
 	// Close closes the queue for any write operations.
 	//
@@ -26,81 +86,433 @@ type Queue interface {
 	Close(timeOut time.Duration)
 }
 
-type queue struct {
-	in         chan interface{}
-	out        chan interface{}
+type queue[T any] struct {
+	in         chan T
+	front      chan T
+	frontDone  chan struct{}
+	out        chan T
+	takes      chan takeRequest[T]
 	forceClose chan struct{}
 	done       chan struct{}
 	once       sync.Once
+
+	// closing is closed as soon as Close is invoked, to release the blocked pushes.
+	closing chan struct{}
+	closed  bool
+	mu      sync.RWMutex
+
+	// slots holds a token for every value pushed but not yet polled.
+	// It is nil for unbounded queues.
+	slots  chan struct{}
+	policy OverflowPolicy
+
+	// priority is nil, unless the values are polled by their priority.
+	priority func(T) int
+
+	// placed is signalled once a value pushed to a priority queue is placed in the backlog, it is nil otherwise.
+	// Hence, a value is polled by its priority as soon as its push returns.
+	placed chan struct{}
+}
+
+// takeRequest asks manage to hand over up to max pending values at once.
+type takeRequest[T any] struct {
+	max    int
+	values chan []T
+}
+
+// Options configures the queue created by NewQueueWithOptions.
+type Options struct {
+
+	// Capacity is the maximum count of elements the queue can hold.
+	// A Capacity less than 1 makes the queue unbounded.
+	Capacity int
+
+	// Policy decides how a push to a full queue is handled, when it is bounded.
+	Policy OverflowPolicy
+
+	// Prefetch is the count of elements handed over ahead of polling.
+	// A larger Prefetch reduces the co-ordination per poll, for high throughput pollers.
+	// A Prefetch less than 1 is considered as 1.
+	Prefetch int
+
+	// Priority makes the queue poll the value of the highest priority first,
+	// the values of the same priority are polled in the order they are pushed.
+	// A push returns once the value is placed by its priority, hence it is polled ahead of the values of lower priority.
+	// If nil, the values are polled in the order they are pushed.
+	Priority func(value interface{}) int
 }
 
 // NewQueue creates a new thread safe queue.
 func NewQueue() Queue {
-	q := queue{
-		in:         make(chan interface{}, 1),
-		out:        make(chan interface{}, 1),
+	return NewQueueWithOptions(Options{})
+}
+
+// NewQueueWithOptions creates a new thread safe queue configured by opts.
+func NewQueueWithOptions(opts Options) Queue {
+	return newQueue[interface{}](opts)
+}
+
+// NewTypedQueue creates a new thread safe queue of values of type T.
+func NewTypedQueue[T any]() TypedQueue[T] {
+	return NewTypedQueueWithOptions[T](Options{})
+}
+
+// NewTypedQueueWithOptions creates a new thread safe queue of values of type T, configured by opts.
+func NewTypedQueueWithOptions[T any](opts Options) TypedQueue[T] {
+	return newQueue[T](opts)
+}
+
+func newQueue[T any](opts Options) *queue[T] {
+	if opts.Prefetch < 1 {
+		opts.Prefetch = 1
+	}
+
+	q := &queue[T]{
+		in:         make(chan T, 1),
+		front:      make(chan T),
+		frontDone:  make(chan struct{}),
+		out:        make(chan T, opts.Prefetch),
+		takes:      make(chan takeRequest[T]),
 		forceClose: make(chan struct{}),
 		done:       make(chan struct{}),
+		closing:    make(chan struct{}),
+		policy:     opts.Policy,
+	}
+
+	if opts.Capacity > 0 {
+		q.slots = make(chan struct{}, opts.Capacity)
+	}
+
+	if opts.Priority != nil {
+		q.priority = func(value T) int { return opts.Priority(value) }
+		q.in = make(chan T)
+		q.placed = make(chan struct{})
 	}
+
 	go q.manage()
 
-	return &q
+	return q
+}
+
+// newBacklog creates the backlog holding the values pending in the queue.
+func (q *queue[T]) newBacklog() backlog[T] {
+	if q.priority != nil {
+		return &prioritized[T]{priority: q.priority}
+	}
+
+	return &fifo[T]{}
 }
 
-func (q *queue) manage() {
-	queue := []interface{}{}
+func (q *queue[T]) manage() {
+	pending := q.newBacklog()
+	in := q.in
+
+	// A pushed value can be of higher priority than the values handed over to out.
+	_, reorder := pending.(*prioritized[T])
 
 	// Done to be closed at the last, as it itimidates the queue has been successfully closed.
 	defer close(q.done)
 
 	defer close(q.out)
 
+	// reclaim takes back the values handed over to out, to the head of the queue.
+	reclaim := func() {
+		prefetched := []T{}
+		for more := true; more; {
+			select {
+			case v := <-q.out:
+				prefetched = append(prefetched, v)
+			default:
+				more = false
+			}
+		}
+
+		for i := len(prefetched) - 1; i >= 0; i-- {
+			pending.pushFront(prefetched[i])
+		}
+	}
+
+	// push adds the value received from in, the pusher waits on placed for priority queues.
+	push := func(value T) {
+		if reorder {
+			reclaim()
+		}
+		pending.push(value)
+
+		if q.placed != nil {
+			q.placed <- struct{}{}
+		}
+	}
+
+	// handOver removes up to max values from the head of the queue.
+	handOver := func(max int) []T {
+		reclaim()
+
+		// The values already pushed should be visible to the request.
+		for pushed := true; pushed && in != nil; {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					in = nil
+					break
+				}
+				push(v)
+			default:
+				pushed = false
+			}
+		}
+
+		values := make([]T, 0, max)
+		for len(values) < max && pending.len() > 0 {
+			values = append(values, pending.pop())
+		}
+
+		return values
+	}
+
+	// pushFront adds the value ahead of the values handed over to out.
+	pushFront := func(value T) {
+		reclaim()
+		pending.pushFront(value)
+	}
+
 	for {
-		if len(queue) == 0 {
+		if pending.len() == 0 {
+			// Input is closed and all the data has been polled.
+			if in == nil {
+				return
+			}
+
 			select {
 			case <-q.forceClose:
 				return
-			case v, ok := <-q.in:
+			case v, ok := <-in:
 				// If channel gets closed, then return
 				if !ok {
 					return
 				}
-				queue = append(queue, v)
+				push(v)
+			case v := <-q.front:
+				pushFront(v)
+				q.frontDone <- struct{}{}
+			case req := <-q.takes:
+				req.values <- handOver(req.max)
 			}
 		} else {
 			select {
 			case <-q.forceClose:
 				return
-			case v, ok := <-q.in:
-				if ok {
-					queue = append(queue, v)
+			case v, ok := <-in:
+				if !ok {
+					// A closed channel is always ready, stop selecting on it
+					// so that the pending data is drained without spinning.
+					in = nil
+					continue
 				}
-			case q.out <- queue[0]:
-				queue[0] = nil
-				queue = queue[1:]
+				push(v)
+			case q.out <- pending.peek():
+				pending.pop()
+			case v := <-q.front:
+				pushFront(v)
+				q.frontDone <- struct{}{}
+			case req := <-q.takes:
+				req.values <- handOver(req.max)
 			}
 		}
 	}
 }
 
-func (q *queue) Push(value interface{}) {
+// take is the non blocking way to obtain up to max values from the queue.
+// closed is true, if the queue is closed and has no pending data.
+func (q *queue[T]) take(max int) (values []T, closed bool) {
+	req := takeRequest[T]{max: max, values: make(chan []T, 1)}
+
+	select {
+	case q.takes <- req:
+		values = <-req.values
+		q.release(len(values))
+		return values, false
+	case <-q.done:
+		// The queue is closed, only the values handed over to out can be pending.
+		for val, ok := <-q.out; ok; val, ok = <-q.out {
+			values = append(values, val)
+			if len(values) == max {
+				break
+			}
+		}
+		q.release(len(values))
+		return values, len(values) == 0
+	}
+}
+
+func (q *queue[T]) Push(value T) error {
+	return q.PushContext(context.Background(), value)
+}
+
+func (q *queue[T]) PushContext(ctx context.Context, value T) error {
+	if err := q.acquire(ctx, q.policy); err != nil {
+		if err == errDropped {
+			return nil
+		}
+		return err
+	}
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		q.release(1)
+		return ErrClosed
+	}
+
 	q.in <- value
+	if q.placed != nil {
+		<-q.placed
+	}
+
+	return nil
 }
 
-func (q *queue) Poll() (interface{}, bool) {
+func (q *queue[T]) PushFront(value T) error {
+	if err := q.acquire(context.Background(), Block); err != nil {
+		return err
+	}
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		q.release(1)
+		return ErrClosed
+	}
+
+	// Waits until manage places the value, as a prefetched value can be polled in the meantime.
+	q.front <- value
+	<-q.frontDone
+	return nil
+}
+
+func (q *queue[T]) Poll() (T, bool) {
 	val, ok := <-q.out
+	if ok {
+		q.release(1)
+	}
 	return val, ok
 }
 
-func (q *queue) induceForceClose() {
+func (q *queue[T]) PollContext(ctx context.Context) (T, error) {
+	var zero T
+
+	select {
+	case val, ok := <-q.out:
+		if !ok {
+			return zero, ErrClosed
+		}
+		q.release(1)
+		return val, nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+func (q *queue[T]) TryPoll() (T, bool, bool) {
+	select {
+	case val, ok := <-q.out:
+		if ok {
+			q.release(1)
+		}
+		return val, ok, !ok
+	default:
+	}
+
+	// The data might still be held by manage, hence it is requested directly.
+	values, closed := q.take(1)
+	if len(values) == 0 {
+		var zero T
+		return zero, false, closed
+	}
+
+	return values[0], true, false
+}
+
+func (q *queue[T]) PollTimeout(timeout time.Duration) (T, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var zero T
+
+	select {
+	case val, ok := <-q.out:
+		if !ok {
+			return zero, ErrClosed
+		}
+		q.release(1)
+		return val, nil
+	case <-timer.C:
+		return zero, ErrTimeout
+	}
+}
+
+func (q *queue[T]) PollBatch(max int, wait time.Duration) ([]T, bool) {
+	if max < 1 {
+		max = 1
+	}
+
+	first, ok := <-q.out
+	if !ok {
+		return nil, false
+	}
+	q.release(1)
+
+	values := make([]T, 1, max)
+	values[0] = first
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for len(values) < max {
+		// The pending data is handed over at once, rather than one by one through out.
+		pending, closed := q.take(max - len(values))
+		values = append(values, pending...)
+
+		if closed || len(values) >= max {
+			break
+		}
+
+		select {
+		case val, ok := <-q.out:
+			if !ok {
+				return values, true
+			}
+			q.release(1)
+			values = append(values, val)
+		case <-timer.C:
+			return values, true
+		}
+	}
+
+	return values, true
+}
+
+func (q *queue[T]) induceForceClose() {
 	close(q.forceClose)
-	<-q.out
+
+	// Discards the values handed over to out.
+	for range q.out {
+	}
 	<-q.done
 }
 
-func (q *queue) Close(timeout time.Duration) {
+func (q *queue[T]) Close(timeout time.Duration) {
 	q.once.Do(func() {
+		close(q.closing)
+
+		// Ensures no push is in progress, while closing the input.
+		q.mu.Lock()
+		q.closed = true
 		close(q.in)
+		q.mu.Unlock()
+
 		if timeout >= 0 {
 			select {
 			case <-time.After(timeout):
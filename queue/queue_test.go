@@ -1,6 +1,8 @@
 package queue
 
 import (
+	"context"
+	"fmt"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -205,3 +207,400 @@ func TestRoutineForceClose(t *testing.T) {
 		t.Errorf("Worker closed gracefully, expected to close forcefully with pending elements in queue")
 	}
 }
+
+func TestQueuePollContext(t *testing.T) {
+	queue := NewQueue()
+
+	queue.Push(1)
+
+	if val, err := queue.PollContext(context.Background()); err != nil || val != 1 {
+		t.Errorf("Invalid Poll: Expected: 1 <nil>, Obtained: %v %v", val, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := queue.PollContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Invalid Error: Expected: %v, Obtained: %v", context.DeadlineExceeded, err)
+	}
+
+	queue.Close(-1)
+
+	if _, err := queue.PollContext(context.Background()); err != ErrClosed {
+		t.Errorf("Invalid Error: Expected: %v, Obtained: %v", ErrClosed, err)
+	}
+}
+
+func TestQueueTryPoll(t *testing.T) {
+	queue := NewQueue()
+
+	if _, ok, closed := queue.TryPoll(); ok || closed {
+		t.Errorf("TryPoll on empty queue: Expected: false false, Obtained: %v %v", ok, closed)
+	}
+
+	maxValue := 100
+	for i := 0; i < maxValue; i++ {
+		queue.Push(i)
+	}
+
+	// All the pushed data should be visible to TryPoll without waiting.
+	for i := 0; i < maxValue; i++ {
+		if val, ok, _ := queue.TryPoll(); !ok || val != i {
+			t.Fatalf("Invalid TryPoll: Expected: %v true, Obtained: %v %v", i, val, ok)
+		}
+	}
+
+	queue.Push(maxValue)
+	queue.Close(-1)
+
+	if val, ok, closed := queue.TryPoll(); !ok || closed || val != maxValue {
+		t.Errorf("Invalid TryPoll: Expected: %v true false, Obtained: %v %v %v", maxValue, val, ok, closed)
+	}
+
+	if _, ok, closed := queue.TryPoll(); ok || !closed {
+		t.Errorf("TryPoll on closed queue: Expected: false true, Obtained: %v %v", ok, closed)
+	}
+}
+
+func TestQueuePollTimeout(t *testing.T) {
+	queue := NewQueue()
+
+	if _, err := queue.PollTimeout(10 * time.Millisecond); err != ErrTimeout {
+		t.Errorf("Invalid Error: Expected: %v, Obtained: %v", ErrTimeout, err)
+	}
+
+	queue.Push(1)
+
+	if val, err := queue.PollTimeout(time.Second); err != nil || val != 1 {
+		t.Errorf("Invalid Poll: Expected: 1 <nil>, Obtained: %v %v", val, err)
+	}
+
+	queue.Close(-1)
+
+	if _, err := queue.PollTimeout(time.Second); err != ErrClosed {
+		t.Errorf("Invalid Error: Expected: %v, Obtained: %v", ErrClosed, err)
+	}
+}
+
+func TestQueuePollBatch(t *testing.T) {
+	queue := NewQueue()
+
+	maxValue := 25
+	for i := 0; i < maxValue; i++ {
+		queue.Push(i)
+	}
+
+	lastVal := 0
+	for _, expectedLen := range []int{10, 10, 5} {
+		values, ok := queue.PollBatch(10, 10*time.Millisecond)
+		if !ok || len(values) != expectedLen {
+			t.Fatalf("Invalid Batch: Expected Length: %d, Obtained: %d %v", expectedLen, len(values), ok)
+		}
+
+		for _, v := range values {
+			if v != lastVal {
+				t.Errorf("Invalid Value: Last: %v, Current: %v\n", lastVal, v)
+			}
+			lastVal++
+		}
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		queue.Push(maxValue)
+		queue.Close(-1)
+	}()
+
+	// Blocks until the first element is available.
+	if values, ok := queue.PollBatch(10, 0); !ok || len(values) != 1 || values[0] != maxValue {
+		t.Errorf("Invalid Batch: Expected: [%d] true, Obtained: %v %v", maxValue, values, ok)
+	}
+
+	if values, ok := queue.PollBatch(10, 0); ok || values != nil {
+		t.Errorf("PollBatch on closed queue: Expected: [] false, Obtained: %v %v", values, ok)
+	}
+}
+
+func TestBoundedQueuePolicies(t *testing.T) {
+	tests := []struct {
+		policy   OverflowPolicy
+		err      error
+		expected []interface{}
+	}{
+		{policy: DropNewest, err: nil, expected: []interface{}{0, 1, 2}},
+		{policy: DropOldest, err: nil, expected: []interface{}{2, 3, 4}},
+		{policy: Reject, err: ErrFull, expected: []interface{}{0, 1, 2}},
+	}
+
+	for _, test := range tests {
+		queue := NewBoundedQueue(3, test.policy)
+
+		for i := 0; i < 5; i++ {
+			err := queue.Push(i)
+			if i < 3 && err != nil {
+				t.Errorf("[%d] Push on non full queue: Expected: <nil>, Obtained: %v", test.policy, err)
+			} else if i >= 3 && err != test.err {
+				t.Errorf("[%d] Push on full queue: Expected: %v, Obtained: %v", test.policy, test.err, err)
+			}
+		}
+
+		queue.Close(-1)
+
+		values := []interface{}{}
+		for v, ok := queue.Poll(); ok; v, ok = queue.Poll() {
+			values = append(values, v)
+		}
+
+		if fmt.Sprint(values) != fmt.Sprint(test.expected) {
+			t.Errorf("[%d] Invalid Values: Expected: %v, Obtained: %v", test.policy, test.expected, values)
+		}
+	}
+}
+
+func TestBoundedQueueBlock(t *testing.T) {
+	queue := NewBoundedQueue(1, Block)
+
+	queue.Push(1)
+
+	pushed := make(chan error)
+	go func() {
+		pushed <- queue.Push(2)
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("Push on full queue should block")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if val, ok := queue.Poll(); !ok || val != 1 {
+		t.Errorf("Invalid Poll: Expected: 1 true, Obtained: %v %v", val, ok)
+	}
+
+	if err := <-pushed; err != nil {
+		t.Errorf("Invalid Error: Expected: <nil>, Obtained: %v", err)
+	}
+
+	go func() {
+		pushed <- queue.Push(3)
+	}()
+
+	// Close should release the blocked push.
+	queue.Close(0)
+
+	if err := <-pushed; err != ErrClosed {
+		t.Errorf("Invalid Error: Expected: %v, Obtained: %v", ErrClosed, err)
+	}
+}
+
+func TestQueuePushAfterClose(t *testing.T) {
+	queue := NewQueue()
+	queue.Close(-1)
+
+	if err := queue.Push(1); err != ErrClosed {
+		t.Errorf("Invalid Error: Expected: %v, Obtained: %v", ErrClosed, err)
+	}
+}
+
+func TestQueuePrefetch(t *testing.T) {
+	queue := NewQueueWithOptions(Options{Prefetch: 10})
+
+	maxValue := 100
+	for i := 0; i < maxValue; i++ {
+		queue.Push(i)
+	}
+
+	lastVal := 0
+	if val, ok := queue.Poll(); !ok || val != lastVal {
+		t.Errorf("Invalid Poll: Expected: %v true, Obtained: %v %v", lastVal, val, ok)
+	}
+	lastVal++
+
+	// The prefetched values should be taken before the rest of the queue.
+	values, _ := queue.PollBatch(maxValue, 0)
+	for _, v := range values {
+		if v != lastVal {
+			t.Errorf("Invalid Value: Last: %v, Current: %v\n", lastVal, v)
+		}
+		lastVal++
+	}
+
+	queue.Close(-1)
+
+	if lastVal != maxValue {
+		t.Errorf("Invalid Last Value: %v\n", lastVal)
+	}
+}
+
+func TestQueuePushFront(t *testing.T) {
+	queue := NewQueueWithOptions(Options{Prefetch: 2})
+
+	for i := 0; i <= 3; i++ {
+		queue.Push(i)
+	}
+
+	// Polls the value, while the next value is prefetched.
+	queue.Poll()
+	if err := queue.PushFront(0); err != nil {
+		t.Errorf("Invalid Error: Expected: <nil>, Obtained: %v", err)
+	}
+
+	queue.Close(-1)
+
+	// The value pushed to front should be polled ahead of the prefetched values.
+	lastVal := 0
+	for v, ok := queue.Poll(); ok; v, ok = queue.Poll() {
+		if v != lastVal {
+			t.Errorf("Invalid Value: Last: %v, Current: %v\n", lastVal, v)
+		}
+		lastVal++
+	}
+
+	if lastVal != 4 {
+		t.Errorf("Invalid Last Value: %v\n", lastVal)
+	}
+
+	if err := queue.PushFront(0); err != ErrClosed {
+		t.Errorf("Invalid Error: Expected: %v, Obtained: %v", ErrClosed, err)
+	}
+}
+
+func TestTypedQueue(t *testing.T) {
+	queue := NewTypedQueueWithOptions[int](Options{Capacity: 2, Policy: Reject})
+
+	for i := 1; i <= 2; i++ {
+		if err := queue.Push(i); err != nil {
+			t.Errorf("Invalid Push Error: Expected: <nil> Obtained: %v", err)
+		}
+	}
+
+	if err := queue.Push(3); err != ErrFull {
+		t.Errorf("Invalid Push Error: Expected: %v Obtained: %v", ErrFull, err)
+	}
+
+	// The polled values are of type int, hence they can be added without type assertion.
+	sum := 0
+	values, _ := queue.PollBatch(2, 0)
+	for _, v := range values {
+		sum += v
+	}
+
+	if sum != 3 {
+		t.Errorf("Invalid Sum: Expected: 3 Obtained: %d", sum)
+	}
+
+	queue.Close(-1)
+
+	if v, err := queue.PollTimeout(time.Second); err != ErrClosed || v != 0 {
+		t.Errorf("Invalid Poll: Expected: 0 %v Obtained: %v %v", ErrClosed, v, err)
+	}
+}
+
+func TestQueuePushContext(t *testing.T) {
+	queue := NewBoundedQueue(1, Block)
+	defer queue.Close(0)
+
+	if err := queue.PushContext(context.Background(), 1); err != nil {
+		t.Errorf("Invalid Push Error: Expected: <nil> Obtained: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := queue.PushContext(ctx, 2); err != context.DeadlineExceeded {
+		t.Errorf("Invalid Push Error: Expected: %v Obtained: %v", context.DeadlineExceeded, err)
+	}
+
+	// Once space frees, the waiting push succeeds.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		queue.Poll()
+	}()
+
+	if err := queue.PushContext(context.Background(), 3); err != nil {
+		t.Errorf("Invalid Push Error: Expected: <nil> Obtained: %v", err)
+	}
+
+	if v, err := queue.PollTimeout(time.Second); err != nil || v != 3 {
+		t.Errorf("Invalid Value: Expected: 3 Obtained: %v %v", v, err)
+	}
+}
+
+type prioritizedValue struct {
+	priority int
+	id       int
+}
+
+func (v prioritizedValue) Priority() int {
+	return v.priority
+}
+
+func TestPriorityQueue(t *testing.T) {
+	queue := NewPriorityQueue()
+
+	pushed := []prioritizedValue{{0, 1}, {2, 2}, {1, 3}, {2, 4}, {0, 5}}
+	for _, v := range pushed {
+		queue.Push(v)
+	}
+
+	// The value of the highest priority is polled first, the rest being ahead of it.
+	if err := queue.PushFront(prioritizedValue{1, 6}); err != nil {
+		t.Errorf("Invalid Error: Expected: <nil>, Obtained: %v", err)
+	}
+
+	queue.Close(-1)
+
+	expected := []int{2, 4, 6, 3, 1, 5}
+	obtained := []int{}
+	for v, ok := queue.Poll(); ok; v, ok = queue.Poll() {
+		obtained = append(obtained, v.(prioritizedValue).id)
+	}
+
+	if fmt.Sprint(obtained) != fmt.Sprint(expected) {
+		t.Errorf("Invalid Order: Expected: %v, Obtained: %v", expected, obtained)
+	}
+}
+
+func TestPriorityQueuePushThenPoll(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		queue := NewPriorityQueue()
+
+		// The value of lower priority is handed over for polling, before the value of higher priority is pushed.
+		queue.Push(prioritizedValue{0, 1})
+		queue.Push(prioritizedValue{0, 2})
+		queue.Push(prioritizedValue{5, 3})
+
+		if v, err := queue.PollTimeout(time.Second); err != nil || v.(prioritizedValue).id != 3 {
+			t.Fatalf("Invalid Poll: Expected: 3 <nil>, Obtained: %v %v", v, err)
+		}
+
+		queue.Close(0)
+	}
+}
+
+func TestPriorityQueuePrefetch(t *testing.T) {
+	queue := NewQueueWithOptions(Options{Prefetch: 4, Priority: priorityOf})
+	defer queue.Close(0)
+
+	for i := 1; i <= 4; i++ {
+		queue.Push(prioritizedValue{0, i})
+	}
+
+	// The prefetched values are taken back, so that the value of higher priority is polled next.
+	queue.Push(prioritizedValue{1, 5})
+
+	if v, err := queue.PollTimeout(time.Second); err != nil || v.(prioritizedValue).id != 5 {
+		t.Errorf("Invalid Poll: Expected: 5 <nil>, Obtained: %v %v", v, err)
+	}
+
+	values, _ := queue.PollBatch(4, 0)
+	if len(values) != 4 {
+		t.Errorf("Invalid Batch Size: Expected: 4, Obtained: %d", len(values))
+	}
+
+	for i, v := range values {
+		if v.(prioritizedValue).id != i+1 {
+			t.Errorf("Invalid Value: Expected: %v, Obtained: %v", i+1, v)
+		}
+	}
+}
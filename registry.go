@@ -0,0 +1,70 @@
+package gomq
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrTypeMismatch is returned while publishing, when the type of the payload isn't the type registered for its topic.
+var ErrTypeMismatch = errors.New("gomq: payload type mismatch")
+
+// Registry declares the type of payload published to topics.
+// A broker created with WithRegistry rejects the payloads not of the type registered for their topic.
+// Hence, a wrong type is caught by the publisher rather than panicking the subscribers.
+//
+// It is safe for concurrent use, topics can be registered while publishing.
+type Registry struct {
+	mu      sync.RWMutex
+	schemas []schema
+}
+
+// schema is a type registered for the topics matched by matcher.
+type schema struct {
+	matcher Matcher
+	typ     reflect.Type
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register declares typ as the type of payload published to the topics matched by topic.
+//
+// If typ is an interface type, the payload can be of any type implementing it, or nil.
+// If a topic is matched by many registrations, the payload should satisfy all of them.
+// The topics not matched by any registration accept payloads of any type.
+func (r *Registry) Register(topic Matcher, typ reflect.Type) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.schemas = append(r.schemas, schema{matcher: topic, typ: typ})
+}
+
+// RegisterType is similar to Registry.Register, but declares T as the type of payload.
+func RegisterType[T any](r *Registry, topic Matcher) {
+	r.Register(topic, reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// Validate returns an error wrapping ErrTypeMismatch, if the payload isn't of the type registered for the topic.
+func (r *Registry) Validate(topic string, payload interface{}) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	typ := reflect.TypeOf(payload)
+	for _, s := range r.schemas {
+		if !s.matcher.MatchString(topic) {
+			continue
+		}
+
+		if typ == s.typ || (s.typ.Kind() == reflect.Interface && (typ == nil || typ.Implements(s.typ))) {
+			continue
+		}
+
+		return fmt.Errorf("%w: topic %q expects %v, obtained %v", ErrTypeMismatch, topic, s.typ, typ)
+	}
+
+	return nil
+}
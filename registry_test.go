@@ -0,0 +1,42 @@
+package gomq
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	RegisterType[int](registry, ExactMatcher("users.count"))
+	RegisterType[fmt.Stringer](registry, MustTopicMatcher("users.#"))
+	RegisterType[error](registry, ExactMatcher("errors"))
+	registry.Register(ExactMatcher("users.name"), reflect.TypeOf(""))
+
+	tests := []struct {
+		topic   string
+		payload interface{}
+		valid   bool
+	}{
+		// "users.count" is matched by "users.#" too, hence an int isn't valid as it isn't a fmt.Stringer.
+		{topic: "users.count", payload: 1, valid: false},
+		{topic: "orders.count", payload: int64(1), valid: true},
+		{topic: "users.created", payload: ExactMatcher("user-1"), valid: false},
+		{topic: "users.created", payload: MustTopicMatcher("user-1"), valid: true},
+		{topic: "users.created", payload: nil, valid: true},
+		{topic: "users.name", payload: "user-1", valid: false},
+		{topic: "errors", payload: ErrClosed, valid: true},
+		{topic: "errors", payload: nil, valid: true},
+		{topic: "errors", payload: "failed", valid: false},
+	}
+
+	for _, test := range tests {
+		err := registry.Validate(test.topic, test.payload)
+		if test.valid && err != nil {
+			t.Errorf("Invalid Validation for %T on %q: Expected: <nil> Obtained: %v", test.payload, test.topic, err)
+		} else if !test.valid && !errors.Is(err, ErrTypeMismatch) {
+			t.Errorf("Invalid Validation for %T on %q: Expected: %v Obtained: %v", test.payload, test.topic, ErrTypeMismatch, err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package gomq
+
+import "time"
+
+// unsafeRetain keeps the message as the last value of its topic.
+// It is called while holding the lock, hence a subscription created meanwhile receives the message either as retained or as published.
+func (b *brokerBase) unsafeRetain(msg *Message) {
+	if msg.Payload == nil {
+		delete(b.retained, msg.Topic)
+		return
+	}
+
+	if b.retained == nil {
+		b.retained = map[string]*Message{}
+	}
+
+	b.retained[msg.Topic] = msg
+}
+
+// unsafeRetained returns the retained messages matching the new subscription.
+// It is called while holding the lock.
+func (b *brokerBase) unsafeRetained(qm *queueMatcher) []*Message {
+	if len(b.retained) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	matched := []*Message{}
+	for topic, msg := range b.retained {
+		if msg.expired(0, now) {
+			delete(b.retained, topic)
+			continue
+		}
+
+		if qm.matches(msg) {
+			matched = append(matched, msg)
+		}
+	}
+
+	return matched
+}
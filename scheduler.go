@@ -0,0 +1,146 @@
+package gomq
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// scheduler publishes the scheduled messages once they are due.
+//
+// The messages are held in a heap ordered by their due time, which is served by a single routine with a single timer.
+// The routine is started on scheduling the first message, and stopped on closing the broker.
+type scheduler struct {
+	mu      sync.Mutex
+	items   scheduledHeap
+	seq     uint64
+	started bool
+	stopped bool
+
+	// wake is signalled when a message is due earlier than the rest.
+	wake chan struct{}
+	done chan struct{}
+
+	publish func(*Message)
+}
+
+type scheduledMessage struct {
+	at  time.Time
+	seq uint64
+	msg *Message
+}
+
+// scheduledHeap implements heap.Interface, the messages due at the same time are ordered by their scheduling.
+type scheduledHeap []scheduledMessage
+
+func (h scheduledHeap) Len() int { return len(h) }
+
+func (h scheduledHeap) Less(i, j int) bool {
+	if h[i].at.Equal(h[j].at) {
+		return h[i].seq < h[j].seq
+	}
+
+	return h[i].at.Before(h[j].at)
+}
+
+func (h scheduledHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *scheduledHeap) Push(x interface{}) { *h = append(*h, x.(scheduledMessage)) }
+
+func (h *scheduledHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = scheduledMessage{}
+	*h = old[:len(old)-1]
+	return item
+}
+
+func newScheduler(publish func(*Message)) *scheduler {
+	return &scheduler{
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		publish: publish,
+	}
+}
+
+// schedule publishes the message at the time.
+// It returns ErrBrokerClosed, if the scheduler is stopped.
+func (s *scheduler) schedule(msg *Message, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return ErrBrokerClosed
+	}
+
+	s.seq++
+	heap.Push(&s.items, scheduledMessage{at: at, seq: s.seq, msg: msg})
+
+	if !s.started {
+		s.started = true
+		go s.run()
+	} else if s.items[0].seq == s.seq {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// stop stops the scheduler, the messages yet to be due are discarded.
+func (s *scheduler) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return
+	}
+
+	s.stopped = true
+	s.items = nil
+	close(s.done)
+}
+
+func (s *scheduler) run() {
+	for {
+		s.mu.Lock()
+
+		now := time.Now()
+		due := []*Message{}
+		for len(s.items) > 0 && !s.items[0].at.After(now) {
+			due = append(due, heap.Pop(&s.items).(scheduledMessage).msg)
+		}
+
+		// A nil channel never fires, hence the routine waits for a message to be scheduled.
+		var timer *time.Timer
+		var next <-chan time.Time
+		if len(s.items) > 0 {
+			timer = time.NewTimer(s.items[0].at.Sub(now))
+			next = timer.C
+		}
+
+		s.mu.Unlock()
+
+		for _, msg := range due {
+			s.publish(msg)
+		}
+
+		select {
+		case <-next:
+		case <-s.wake:
+		case <-s.done:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package gomq
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Subscription is a Poller attached to a Broker.
+// Unlike Broker.Close, it can be detached from the broker without affecting other subscribers.
+type Subscription interface {
+	Poller
+	MessagePoller
+
+	// Unsubscribe removes the subscription from its broker and closes its queue.
+	// Data published afterwards will not be delivered to this subscription.
+	//
+	// For a consumer group, the shared queue is removed & closed once all its members have unsubscribed.
+	// The member shouldn't be polled once unsubscribed.
+	//
+	// If timeOut < 0, then resources will be closed once
+	// the queue is empty.
+	// For any timeOut >= 0, the resources will be force closed
+	// after timeOut.
+	Unsubscribe(timeOut time.Duration)
+
+	// Detach releases the subscription, without removing a durable subscription from its broker.
+	// Hence, the data published meanwhile is held until it is subscribed again with its name.
+	// A detached durable subscription can be removed by subscribing again, and unsubscribing.
+	//
+	// For a subscription which isn't durable, it is same as Unsubscribe(0).
+	// The subscription shouldn't be polled once detached.
+	Detach()
+
+	// Name returns the name of the subscription.
+	Name() string
+
+	// PollDelivery is similar to PollMessage, but returns the message as a Delivery.
+	// For subscriptions in acknowledgement mode, the delivery is redelivered unless acknowledged within the deadline.
+	PollDelivery() (*Delivery, bool)
+
+	// PollDeliveryContext is similar to PollMessageContext, but returns the message as a Delivery.
+	PollDeliveryContext(ctx context.Context) (*Delivery, error)
+
+	// Stats returns the statistics of the subscription.
+	Stats() SubscriptionStats
+}
+
+// SubscriptionStats holds the statistics of a Subscription.
+type SubscriptionStats struct {
+
+	// Expired is the count of messages discarded, as they expired before being polled.
+	Expired uint64
+
+	// DeadLettersDropped is the count of messages, which couldn't be published to all the subscribers
+	// of the dead letter topic, as the Registry rejected them or a subscription was full.
+	DeadLettersDropped uint64
+}
+
+// subscriptionStats is updated atomically, as the subscription can be polled by many routines.
+type subscriptionStats struct {
+	expired            uint64
+	deadLettersDropped uint64
+}
+
+// subscription polls the messages from its queue.
+// The Poller functions return only the payload of the messages.
+type subscription struct {
+	*queueMatcher
+	broker *brokerBase
+	once   sync.Once
+}
+
+func (s *subscription) Name() string {
+	return s.name
+}
+
+func (s *subscription) Unsubscribe(timeOut time.Duration) {
+	s.once.Do(func() {
+		s.unsubscribe(timeOut)
+	})
+}
+
+func (s *subscription) Detach() {
+	s.once.Do(func() {
+		if !s.broker.detach(s.queueMatcher) {
+			s.unsubscribe(0)
+		}
+	})
+}
+
+func (s *subscription) unsubscribe(timeOut time.Duration) {
+	if s.broker.unsubscribe(s.queue) {
+		s.queue.Close(timeOut)
+		s.tracker.close()
+	}
+}
+
+func (s *subscription) Stats() SubscriptionStats {
+	return SubscriptionStats{
+		Expired:            atomic.LoadUint64(&s.stats.expired),
+		DeadLettersDropped: atomic.LoadUint64(&s.stats.deadLettersDropped),
+	}
+}
+
+// message returns the message of the value polled from the queue.
+// The queue holds the redelivered messages as deliveries.
+func message(val interface{}) *Message {
+	if d, ok := val.(*Delivery); ok {
+		return d.Message
+	}
+
+	return val.(*Message)
+}
+
+// expired discards the value polled from the queue, if its message has expired.
+func (s *subscription) expired(val interface{}) bool {
+	msg, attempt := message(val), 0
+	if msg.TTL <= 0 && s.ttl <= 0 {
+		return false
+	}
+
+	if !msg.expired(s.ttl, time.Now()) {
+		return false
+	}
+
+	if d, ok := val.(*Delivery); ok {
+		attempt = d.Attempt
+	}
+
+	atomic.AddUint64(&s.stats.expired, 1)
+	s.deadLetter.send(msg, attempt, ReasonExpired)
+	return true
+}
+
+// poll polls the queue until a value, which hasn't expired is obtained.
+func (s *subscription) poll() (interface{}, bool) {
+	for {
+		val, ok := s.queue.Poll()
+		if !ok || !s.expired(val) {
+			return val, ok
+		}
+	}
+}
+
+// pollContext is similar to poll, but returns once ctx is done.
+func (s *subscription) pollContext(ctx context.Context) (interface{}, error) {
+	for {
+		val, err := s.queue.PollContext(ctx)
+		if err != nil || !s.expired(val) {
+			return val, err
+		}
+	}
+}
+
+// deliver creates a delivery for the value polled from the queue.
+func (s *subscription) deliver(val interface{}) *Delivery {
+	msg, attempt := message(val), 1
+	if d, ok := val.(*Delivery); ok {
+		attempt = d.Attempt + 1
+	}
+
+	if s.tracker == nil {
+		return &Delivery{Message: msg, Attempt: attempt, deadLetter: s.deadLetter}
+	}
+
+	return s.tracker.track(msg, attempt)
+}
+
+func (s *subscription) PollDelivery() (*Delivery, bool) {
+	val, ok := s.poll()
+	if !ok {
+		return nil, false
+	}
+
+	return s.deliver(val), true
+}
+
+func (s *subscription) PollDeliveryContext(ctx context.Context) (*Delivery, error) {
+	val, err := s.pollContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.deliver(val), nil
+}
+
+func (s *subscription) PollMessage() (*Message, bool) {
+	val, ok := s.poll()
+	if !ok {
+		return nil, false
+	}
+
+	return message(val), true
+}
+
+func (s *subscription) PollMessageContext(ctx context.Context) (*Message, error) {
+	val, err := s.pollContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return message(val), nil
+}
+
+func (s *subscription) Poll() (interface{}, bool) {
+	msg, ok := s.PollMessage()
+	if !ok {
+		return nil, false
+	}
+
+	return msg.Payload, true
+}
+
+func (s *subscription) PollContext(ctx context.Context) (interface{}, error) {
+	msg, err := s.PollMessageContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg.Payload, nil
+}
+
+func (s *subscription) TryPoll() (interface{}, bool, bool) {
+	for {
+		val, ok, closed := s.queue.TryPoll()
+		if !ok {
+			return nil, false, closed
+		}
+
+		if !s.expired(val) {
+			return message(val).Payload, true, false
+		}
+	}
+}
+
+func (s *subscription) PollTimeout(timeOut time.Duration) (interface{}, error) {
+	deadline := time.Now().Add(timeOut)
+
+	for {
+		val, err := s.queue.PollTimeout(time.Until(deadline))
+		if err != nil {
+			return nil, err
+		}
+
+		if !s.expired(val) {
+			return message(val).Payload, nil
+		}
+	}
+}
+
+func (s *subscription) PollBatch(max int, wait time.Duration) ([]interface{}, bool) {
+	for {
+		values, ok := s.queue.PollBatch(max, wait)
+		if !ok {
+			return nil, false
+		}
+
+		// The expired values are removed in place.
+		payloads := values[:0]
+		for _, val := range values {
+			if !s.expired(val) {
+				payloads = append(payloads, message(val).Payload)
+			}
+		}
+
+		if len(payloads) > 0 {
+			return payloads, true
+		}
+	}
+}
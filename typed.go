@@ -0,0 +1,169 @@
+package gomq
+
+import (
+	"context"
+	"time"
+)
+
+// TypedPoller is similar to Poller, but polls data of type T.
+// Hence, the polled data need not be type asserted.
+type TypedPoller[T any] interface {
+
+	// Poll is similar to Poller.Poll.
+	// If the resource is closed, then Poll will return the zero value and False.
+	Poll() (T, bool)
+
+	// PollContext is similar to Poller.PollContext.
+	PollContext(ctx context.Context) (T, error)
+
+	// TryPoll is similar to Poller.TryPoll.
+	TryPoll() (data T, ok bool, closed bool)
+
+	// PollTimeout is similar to Poller.PollTimeout.
+	PollTimeout(timeOut time.Duration) (T, error)
+
+	// PollBatch is similar to Poller.PollBatch.
+	PollBatch(max int, wait time.Duration) ([]T, bool)
+}
+
+// TypedSubscription is a TypedPoller attached to a TypedBroker.
+type TypedSubscription[T any] interface {
+	TypedPoller[T]
+
+	// Unsubscribe is similar to Subscription.Unsubscribe.
+	Unsubscribe(timeOut time.Duration)
+
+	// Detach is similar to Subscription.Detach.
+	Detach()
+
+	// Name returns the name of the subscription.
+	Name() string
+
+	// Stats returns the statistics of the subscription.
+	Stats() SubscriptionStats
+}
+
+// TypedBroker is similar to Broker, but exchanges data of type T.
+// Hence, publishing data of any other type fails at compile time rather than panicking on polling.
+//
+// It is a type safe wrapper over Broker, the data is still published in a Message.
+// Hence, unlike TypedQueue, it doesn't avoid boxing the data of value types.
+type TypedBroker[T any] interface {
+
+	// Publish is similar to Broker.Publish.
+	Publish(topic string, data T) int
+
+	// PublishE is similar to Broker.PublishE.
+	PublishE(topic string, data T) (int, error)
+
+	// PublishContext is similar to Broker.PublishContext.
+	PublishContext(ctx context.Context, topic string, data T) (int, error)
+
+	// PublishAt is similar to Broker.PublishAt.
+	PublishAt(topic string, data T, at time.Time) error
+
+	// PublishAfter is similar to Broker.PublishAfter.
+	PublishAfter(topic string, data T, delay time.Duration) error
+
+	// Subscribe is similar to Broker.Subscribe.
+	Subscribe(topic Matcher) TypedSubscription[T]
+
+	// SubscribeWithOptions is similar to Broker.SubscribeWithOptions.
+	SubscribeWithOptions(topic Matcher, opts ...SubscribeOption) TypedSubscription[T]
+
+	// Close is similar to Broker.Close.
+	Close(timeOut time.Duration)
+}
+
+// NewTypedBroker creates a TypedBroker, which exchanges data of type T through a broker created by NewBroker.
+func NewTypedBroker[T any]() TypedBroker[T] {
+	return &typedBroker[T]{broker: NewBroker()}
+}
+
+// NewTypedAsyncBroker creates a TypedBroker, which exchanges data of type T through a broker created by NewAsyncBroker.
+func NewTypedAsyncBroker[T any]() TypedBroker[T] {
+	return &typedBroker[T]{broker: NewAsyncBroker()}
+}
+
+// typedBroker is built on a Broker, which is used only through typedBroker.
+// Hence, all the data it holds are of type T.
+type typedBroker[T any] struct {
+	broker Broker
+}
+
+func (b *typedBroker[T]) Publish(topic string, data T) int {
+	return b.broker.Publish(topic, data)
+}
+
+func (b *typedBroker[T]) PublishE(topic string, data T) (int, error) {
+	return b.broker.PublishE(topic, data)
+}
+
+func (b *typedBroker[T]) PublishContext(ctx context.Context, topic string, data T) (int, error) {
+	return b.broker.PublishContext(ctx, topic, data)
+}
+
+func (b *typedBroker[T]) PublishAt(topic string, data T, at time.Time) error {
+	return b.broker.PublishAt(topic, data, at)
+}
+
+func (b *typedBroker[T]) PublishAfter(topic string, data T, delay time.Duration) error {
+	return b.broker.PublishAfter(topic, data, delay)
+}
+
+func (b *typedBroker[T]) Subscribe(topic Matcher) TypedSubscription[T] {
+	return &typedSubscription[T]{Subscription: b.broker.Subscribe(topic)}
+}
+
+func (b *typedBroker[T]) SubscribeWithOptions(topic Matcher, opts ...SubscribeOption) TypedSubscription[T] {
+	return &typedSubscription[T]{Subscription: b.broker.SubscribeWithOptions(topic, opts...)}
+}
+
+func (b *typedBroker[T]) Close(timeOut time.Duration) {
+	b.broker.Close(timeOut)
+}
+
+type typedSubscription[T any] struct {
+	Subscription
+}
+
+// typed converts the polled data to T.
+// The data of a nil interface type is nil, hence it is converted to the zero value.
+func typed[T any](data interface{}) T {
+	val, _ := data.(T)
+	return val
+}
+
+func (s *typedSubscription[T]) Poll() (T, bool) {
+	data, ok := s.Subscription.Poll()
+	return typed[T](data), ok
+}
+
+func (s *typedSubscription[T]) PollContext(ctx context.Context) (T, error) {
+	data, err := s.Subscription.PollContext(ctx)
+	return typed[T](data), err
+}
+
+func (s *typedSubscription[T]) TryPoll() (T, bool, bool) {
+	data, ok, closed := s.Subscription.TryPoll()
+	return typed[T](data), ok, closed
+}
+
+func (s *typedSubscription[T]) PollTimeout(timeOut time.Duration) (T, error) {
+	data, err := s.Subscription.PollTimeout(timeOut)
+	return typed[T](data), err
+}
+
+func (s *typedSubscription[T]) PollBatch(max int, wait time.Duration) ([]T, bool) {
+	values, ok := s.Subscription.PollBatch(max, wait)
+	if !ok {
+		return nil, false
+	}
+
+	data := make([]T, len(values))
+	for i, val := range values {
+		data[i] = typed[T](val)
+	}
+
+	return data, true
+}
@@ -0,0 +1,82 @@
+package gomq
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTypedBroker(t *testing.T) {
+	t.Run("SyncBroker", func(t *testing.T) {
+		testTypedBroker(t, NewTypedBroker[int]())
+	})
+
+	t.Run("AsyncBroker", func(t *testing.T) {
+		testTypedBroker(t, NewTypedAsyncBroker[int]())
+	})
+}
+
+func testTypedBroker(t *testing.T, broker TypedBroker[int]) {
+	sub := broker.Subscribe(ExactMatcher("numbers"))
+	named := broker.SubscribeWithOptions(ExactMatcher("numbers"), WithName("named"))
+
+	for i := 1; i <= 5; i++ {
+		broker.Publish("numbers", i)
+	}
+
+	// The polled data are of type int, hence they can be added without type assertion.
+	sum := 0
+	if v, ok := sub.Poll(); ok {
+		sum += v
+	}
+
+	if v, err := sub.PollContext(context.Background()); err == nil {
+		sum += v
+	}
+
+	if v, err := sub.PollTimeout(time.Second); err == nil {
+		sum += v
+	}
+
+	if values, ok := sub.PollBatch(2, time.Second); ok {
+		for _, v := range values {
+			sum += v
+		}
+	}
+
+	if sum != 15 {
+		t.Errorf("Invalid Sum: Expected: 15 Obtained: %d", sum)
+	}
+
+	if v, ok, closed := sub.TryPoll(); ok || closed || v != 0 {
+		t.Errorf("Invalid TryPoll: Expected: 0 false false Obtained: %v %v %v", v, ok, closed)
+	}
+
+	if named.Name() != "named" {
+		t.Errorf("Invalid Name: Expected: named Obtained: %s", named.Name())
+	}
+
+	named.Unsubscribe(0)
+	broker.Close(-1)
+
+	if v, ok := sub.Poll(); ok || v != 0 {
+		t.Errorf("Invalid Poll After Close: Expected: 0 false Obtained: %v %v", v, ok)
+	}
+}
+
+func TestTypedBrokerInterface(t *testing.T) {
+	broker := NewTypedBroker[error]()
+	defer broker.Close(-1)
+
+	sub := broker.Subscribe(ExactMatcher("errors"))
+	broker.Publish("errors", nil)
+	broker.Publish("errors", ErrClosed)
+
+	if err, ok := sub.Poll(); !ok || err != nil {
+		t.Errorf("Invalid Value: Expected: <nil> true Obtained: %v %v", err, ok)
+	}
+
+	if err, ok := sub.Poll(); !ok || err != ErrClosed {
+		t.Errorf("Invalid Value: Expected: %v true Obtained: %v %v", ErrClosed, err, ok)
+	}
+}